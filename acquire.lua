@@ -0,0 +1,17 @@
+local function acquire(key, id, ttl, limit)
+	local t = redis.call("time")
+	local now = t[1] * 1000 + math.floor(t[2]/1000)
+	redis.call("zremrangebyscore", key, "-inf", now)
+	local counter = redis.call("zcard", key)
+	if counter + 1 > limit then
+		local first = redis.call("zrange", key, 0, 0, "withscores")
+		if first[2] == nil then
+			return { 0, counter, 0 }
+		end
+		return { 0, counter, first[2] - now }
+	end
+	redis.call("zadd", key, now + ttl, id)
+	redis.call("pexpire", key, ttl)
+	return { 1, counter + 1, tonumber(ttl) }
+end
+return acquire(KEYS[1], ARGV[1], ARGV[2], tonumber(ARGV[3]))
\ No newline at end of file
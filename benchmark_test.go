@@ -17,6 +17,9 @@ func BenchmarkCounter(b *testing.B) {
 	tests := map[string]*Counter{
 		"FixedWindow":   FixedWindow(client, size, limit),
 		"SlidingWindow": SlidingWindow(client, size, limit),
+		"TokenBucket":   TokenBucket(client, size/time.Duration(limit), limit),
+		"GCRA":          GCRA(client, size, limit),
+		"SlidingLog":    SlidingLog(client, size, limit),
 	}
 
 	ctx := context.Background()
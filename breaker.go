@@ -0,0 +1,89 @@
+package counter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is the error returned when the circuit breaker does not call the store.
+var ErrCircuitOpen = errors.New("counter: circuit open")
+
+// WithCircuitBreaker makes the limiter or the counter stop calling the store for the cooldown
+// after threshold consecutive calls fail because the store is unavailable.
+// The calls canceled by the caller or failed after the deadline of the caller are not counted.
+// While the circuit is open every call fails with ErrCircuitOpen, so the failure policy applies at once.
+// After the cooldown a single call is made to the store, the circuit is closed if the call succeeds,
+// otherwise it is open for another cooldown.
+func WithCircuitBreaker(threshold uint, cooldown time.Duration) Option {
+	return option(func(c *config) {
+		c.breaker = breaker{threshold: int(threshold), cooldown: cooldown}
+	})
+}
+
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+}
+
+// wrap returns the store guarded by a new circuit breaker if the circuit breaker is set.
+func (b breaker) wrap(store Store) Store {
+	if b.threshold == 0 {
+		return store
+	}
+	return &breakerStore{store: store, breaker: b}
+}
+
+type breakerStore struct {
+	store Store
+	breaker
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func (s *breakerStore) Run(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error) {
+	if !s.allow() {
+		return nil, ErrCircuitOpen
+	}
+	res, err := s.store.Run(ctx, script, keys, args...)
+	s.done(ctx, err)
+	return res, err
+}
+
+// allow checks if the circuit is closed, or the cooldown is over and no other call is probing the store.
+func (s *breakerStore) allow() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures < s.threshold {
+		return true
+	}
+	if s.probing || time.Since(s.openedAt) < s.cooldown {
+		return false
+	}
+	s.probing = true
+	return true
+}
+
+// done records the outcome of the call, the call canceled by the caller, or failed after the deadline
+// of the caller, changes nothing: the store has not caused the failure.
+func (s *breakerStore) done(ctx context.Context, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.probing = false
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	if err == nil || !unavailable(err) {
+		if !errors.Is(err, context.Canceled) {
+			s.failures = 0
+		}
+		return
+	}
+	s.failures++
+	if s.failures >= s.threshold {
+		s.openedAt = time.Now()
+	}
+}
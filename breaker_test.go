@@ -0,0 +1,98 @@
+package counter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemory()
+	calls := 0
+	var e error
+	store := storeFunc(func(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error) {
+		calls++
+		if e != nil {
+			return nil, e
+		}
+		return memory.Run(ctx, script, keys, args...)
+	})
+	x := WithLimit(time.Second, 10, WithName("x"))
+	y := WithLimit(time.Minute, 100, WithName("y"))
+	cooldown := time.Millisecond * 50
+
+	limiter := NewStoreLimiter(store, x, y, WithCircuitBreaker(2, cooldown), WithFailOpen())
+	e = errors.New("connection refused")
+	for i := 0; i < 2; i++ {
+		result, err := limiter.Limit(ctx, "key")
+		require.NoError(t, err)
+		require.True(t, result.Degraded())
+	}
+	require.Equal(t, 2, calls)
+
+	result, err := limiter.Limit(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.True(t, result.Degraded())
+	require.Equal(t, 2, calls)
+
+	time.Sleep(cooldown)
+	_, err = limiter.Limit(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+
+	_, err = limiter.Limit(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+
+	time.Sleep(cooldown)
+	e = nil
+	result, err = limiter.Limit(ctx, "key")
+	require.NoError(t, err)
+	require.False(t, result.Degraded())
+	require.Equal(t, 4, calls)
+
+	result, err = limiter.Limit(ctx, "key")
+	require.NoError(t, err)
+	require.False(t, result.Degraded())
+	require.Equal(t, int64(8), result.Remainder())
+	require.Equal(t, 5, calls)
+
+	c := NewCounter(store, x, WithCircuitBreaker(1, time.Minute))
+	e = errors.New("connection refused")
+	_, err = c.Count(ctx, "key", 1)
+	require.Equal(t, e, err)
+	_, err = c.Count(ctx, "key", 1)
+	require.Equal(t, ErrCircuitOpen, err)
+	require.Equal(t, 6, calls)
+
+	c = NewCounter(store, x, WithCircuitBreaker(1, time.Minute))
+	for _, err := range []error{ErrUnexpectedRedisResponse, context.Canceled} {
+		e = err
+		_, err = c.Count(ctx, "key", 1)
+		require.Equal(t, e, err)
+	}
+	require.Equal(t, 8, calls)
+
+	c = NewCounter(store, x, WithCircuitBreaker(1, time.Minute))
+	dctx, cancel := context.WithDeadline(ctx, time.Now())
+	defer cancel()
+	e = context.DeadlineExceeded
+	_, err = c.Count(dctx, "key", 1) // the deadline of the caller
+	require.Equal(t, e, err)
+	e = nil
+	_, err = c.Count(ctx, "key", 1)
+	require.NoError(t, err)
+	require.Equal(t, 10, calls)
+
+	e = context.DeadlineExceeded
+	_, err = c.Count(ctx, "key", 1) // the deadline of the store
+	require.Equal(t, e, err)
+	_, err = c.Count(ctx, "key", 1)
+	require.Equal(t, ErrCircuitOpen, err)
+	require.Equal(t, 11, calls)
+}
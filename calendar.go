@@ -0,0 +1,49 @@
+package counter
+
+import (
+	"strconv"
+	"time"
+)
+
+// Period is calendar period of a quota.
+type Period int
+
+const (
+	// Day is calendar day.
+	Day Period = iota + 1
+	// Week is calendar week starting on Monday.
+	Week
+	// Month is calendar month.
+	Month
+	// Year is calendar year.
+	Year
+)
+
+type calendar struct {
+	period Period
+	loc    *time.Location
+}
+
+// window returns the key of the calendar period containing the time,
+// and the time in milliseconds until the end of the period.
+func (c *calendar) window(key string, now time.Time) (string, int) {
+	now = now.In(c.loc)
+	y, m, d := now.Date()
+	var start, end time.Time
+	switch c.period {
+	case Day:
+		start = time.Date(y, m, d, 0, 0, 0, 0, c.loc)
+		end = time.Date(y, m, d+1, 0, 0, 0, 0, c.loc)
+	case Week:
+		d -= (int(now.Weekday()) + 6) % 7
+		start = time.Date(y, m, d, 0, 0, 0, 0, c.loc)
+		end = time.Date(y, m, d+7, 0, 0, 0, 0, c.loc)
+	case Month:
+		start = time.Date(y, m, 1, 0, 0, 0, 0, c.loc)
+		end = time.Date(y, m+1, 1, 0, 0, 0, 0, c.loc)
+	default:
+		start = time.Date(y, 1, 1, 0, 0, 0, 0, c.loc)
+		end = time.Date(y+1, 1, 1, 0, 0, 0, 0, c.loc)
+	}
+	return key + ":" + strconv.FormatInt(start.Unix(), 10), int((end.Sub(now) + time.Millisecond - 1) / time.Millisecond)
+}
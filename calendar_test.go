@@ -0,0 +1,109 @@
+package counter
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendar(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		period Period
+		now    time.Time
+		start  time.Time
+		ttl    time.Duration
+	}{
+		"Day": {
+			period: Day,
+			now:    time.Date(2021, 3, 27, 18, 0, 0, 0, loc),
+			start:  time.Date(2021, 3, 27, 0, 0, 0, 0, loc),
+			ttl:    6 * time.Hour,
+		},
+		"DayDSTStart": {
+			period: Day,
+			now:    time.Date(2021, 3, 28, 1, 0, 0, 0, loc),
+			start:  time.Date(2021, 3, 28, 0, 0, 0, 0, loc),
+			ttl:    22 * time.Hour,
+		},
+		"DayDSTEnd": {
+			period: Day,
+			now:    time.Date(2021, 10, 31, 1, 0, 0, 0, loc),
+			start:  time.Date(2021, 10, 31, 0, 0, 0, 0, loc),
+			ttl:    24 * time.Hour,
+		},
+		"Week": {
+			period: Week,
+			now:    time.Date(2021, 3, 24, 12, 0, 0, 0, loc),
+			start:  time.Date(2021, 3, 22, 0, 0, 0, 0, loc),
+			ttl:    4*24*time.Hour + 12*time.Hour - time.Hour,
+		},
+		"WeekSunday": {
+			period: Week,
+			now:    time.Date(2021, 3, 21, 12, 0, 0, 0, loc),
+			start:  time.Date(2021, 3, 15, 0, 0, 0, 0, loc),
+			ttl:    12 * time.Hour,
+		},
+		"Month": {
+			period: Month,
+			now:    time.Date(2021, 2, 15, 0, 0, 0, 0, loc),
+			start:  time.Date(2021, 2, 1, 0, 0, 0, 0, loc),
+			ttl:    14 * 24 * time.Hour,
+		},
+		"MonthDSTEnd": {
+			period: Month,
+			now:    time.Date(2021, 10, 31, 0, 0, 0, 0, loc),
+			start:  time.Date(2021, 10, 1, 0, 0, 0, 0, loc),
+			ttl:    25 * time.Hour,
+		},
+		"Year": {
+			period: Year,
+			now:    time.Date(2021, 12, 31, 23, 0, 0, 1, loc),
+			start:  time.Date(2021, 1, 1, 0, 0, 0, 0, loc),
+			ttl:    time.Hour,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &calendar{period: tc.period, loc: loc}
+			key, size := c.window("key", tc.now.UTC())
+			require.Equal(t, "key:"+strconv.FormatInt(tc.start.Unix(), 10), key)
+			require.Equal(t, int(tc.ttl/time.Millisecond), size)
+		})
+	}
+}
+
+func TestQuota(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	c := &calendar{period: Day, loc: time.UTC}
+	k, _ := c.window(key, time.Now())
+	err := client.Del(ctx, k).Err()
+	require.NoError(t, err)
+
+	counter := Quota(client, Day, time.UTC, 100)
+
+	result, err := counter.Count(ctx, key, 20)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(20), result.Counter())
+	require.Equal(t, int64(80), result.Remainder())
+	require.True(t, result.TTL() > msToDuration(0) && result.TTL() <= 24*time.Hour)
+
+	result, err = counter.Count(ctx, key, 81)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.Equal(t, int64(20), result.Counter())
+	require.Equal(t, int64(80), result.Remainder())
+	require.True(t, result.TTL() > msToDuration(0) && result.TTL() <= 24*time.Hour)
+}
@@ -0,0 +1,20 @@
+local function cancel(key, value, size, limit, at)
+	local t = redis.call("time")
+	local now = t[1] * 1000 + math.floor(t[2]/1000)
+	if now >= at then
+		return 0
+	end
+	local tat = redis.call("get", key)
+	if tat == false then
+		return 0
+	end
+	tat = math.max(tonumber(tat) - value * size / limit, now)
+	local ttl = math.ceil(tat - now)
+	if ttl > 0 then
+		redis.call("set", key, tat, "px", ttl)
+	else
+		redis.call("del", key)
+	end
+	return 1
+end
+return cancel(KEYS[1], ARGV[1], ARGV[2], tonumber(ARGV[3]), tonumber(ARGV[4]))
\ No newline at end of file
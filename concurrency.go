@@ -0,0 +1,68 @@
+package counter
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"time"
+)
+
+// ConcurrencyLimiter implements distributed concurrency limiting.
+type ConcurrencyLimiter struct {
+	store Store
+	ttl   int
+	limit int64
+}
+
+// NewConcurrencyLimiter creates new limiter which allows no more than limit leases per key at the same time.
+// Each lease expires after ttl unless released, so crashed holders do not leak leases.
+func NewConcurrencyLimiter(client RedisClient, ttl time.Duration, limit uint) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{store: NewRedisStore(client), ttl: int(ttl / time.Millisecond), limit: int64(limit)}
+}
+
+//go:embed acquire.lua
+var acsrc string
+var acscr = newScript("acquire", acsrc)
+
+// Acquire acquires a lease for the key.
+//
+// The counter of the lease result is the number of current lease holders,
+// the TTL is the lease TTL if the lease is acquired, otherwise the time until the first lease expires.
+func (cl *ConcurrencyLimiter) Acquire(ctx context.Context, key string) (*Lease, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	id := hex.EncodeToString(b)
+	res, err := cl.store.Run(ctx, acscr, []string{key}, id, cl.ttl, cl.limit)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newResult(res, cl.limit)
+	if err != nil {
+		return nil, err
+	}
+	return &Lease{Result: r, store: cl.store, key: key, id: id}, nil
+}
+
+// Lease is concurrency limiter lease.
+type Lease struct {
+	Result
+	store Store
+	key   string
+	id    string
+}
+
+//go:embed release.lua
+var rlsrc string
+var rlscr = newScript("release", rlsrc)
+
+// Release releases the lease, does nothing if the lease is not acquired or expired.
+func (l *Lease) Release(ctx context.Context) error {
+	if !l.OK() {
+		return nil
+	}
+	_, err := l.store.Run(ctx, rlscr, []string{l.key}, l.id)
+	return err
+}
@@ -0,0 +1,86 @@
+package counter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	ttl := time.Second
+	limiter := NewConcurrencyLimiter(client, ttl, 2)
+
+	lease1, err := limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	require.True(t, lease1.OK())
+	require.Equal(t, int64(1), lease1.Counter())
+	require.Equal(t, int64(1), lease1.Remainder())
+	require.Equal(t, ttl, lease1.TTL())
+
+	lease2, err := limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	require.True(t, lease2.OK())
+	require.Equal(t, int64(2), lease2.Counter())
+	require.Equal(t, int64(0), lease2.Remainder())
+	require.Equal(t, ttl, lease2.TTL())
+
+	lease3, err := limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	require.False(t, lease3.OK())
+	require.Equal(t, int64(2), lease3.Counter())
+	require.Equal(t, int64(0), lease3.Remainder())
+	require.True(t, lease3.TTL() > msToDuration(0) && lease3.TTL() <= ttl)
+	require.NoError(t, lease3.Release(ctx))
+
+	require.NoError(t, lease1.Release(ctx))
+
+	lease4, err := limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	require.True(t, lease4.OK())
+	require.Equal(t, int64(2), lease4.Counter())
+	require.Equal(t, int64(0), lease4.Remainder())
+	require.Equal(t, ttl, lease4.TTL())
+
+	time.Sleep(ttl + 100*time.Millisecond) // wait for the leases to expire
+
+	lease5, err := limiter.Acquire(ctx, key)
+	require.NoError(t, err)
+	require.True(t, lease5.OK())
+	require.Equal(t, int64(1), lease5.Counter())
+	require.Equal(t, int64(1), lease5.Remainder())
+	require.Equal(t, ttl, lease5.TTL())
+}
+
+func TestConcurrencyLimiterError(t *testing.T) {
+	clientMock := &ClientMock{}
+	limiter := &ConcurrencyLimiter{store: NewRedisStore(clientMock), ttl: 1000, limit: 2}
+	ctx := context.Background()
+	hash := acscr.Hash()
+
+	var i interface{}
+
+	e := errors.New("redis error")
+	clientMock.On("EvalSha", ctx, hash, []string{"1"}, mock.Anything, 1000, int64(2)).Return(redis.NewCmdResult(i, e))
+	_, err := limiter.Acquire(ctx, "1")
+	require.Equal(t, e, err)
+
+	i = []interface{}{int64(1), int64(2)}
+	clientMock.On("EvalSha", ctx, hash, []string{"2"}, mock.Anything, 1000, int64(2)).Return(redis.NewCmdResult(i, nil))
+	_, err = limiter.Acquire(ctx, "2")
+	require.Equal(t, ErrUnexpectedRedisResponse, err)
+
+	clientMock.AssertExpectations(t)
+}
@@ -20,10 +20,13 @@ type RedisClient interface {
 
 // Result is counter value increment result.
 type Result struct {
-	ok      int64
-	counter int64
-	ttl     int64
-	limit   int64
+	ok       int64
+	counter  int64
+	ttl      int64
+	limit    int64
+	limits   []LimitResult
+	degraded bool
+	at       time.Time
 }
 
 // OK is operation success flag.
@@ -46,24 +49,178 @@ func (r Result) TTL() time.Duration {
 	return time.Duration(r.ttl) * time.Millisecond
 }
 
+// window returns the remaining duration of the window of the result in milliseconds rounded up,
+// plus 1 millisecond for the rounding of the store time to milliseconds.
+// The duration is negative if the window has ended, the result created without the store has no window.
+func (r Result) window() int {
+	return millis(r.TTL()-time.Since(r.at)) + 1
+}
+
+// Degraded is true if the result is created by the failure policy because the store is unavailable.
+func (r Result) Degraded() bool {
+	return r.degraded
+}
+
+// Limits are the results of each limit of the limiter, nil for the counter result.
+func (r Result) Limits() []LimitResult {
+	return r.limits
+}
+
+// LimitResult is the result of a single limit of the limiter.
+type LimitResult struct {
+	Result
+	name string
+	alg  string
+}
+
+// Name is the name of the limit set with WithName, empty if the limit is unnamed.
+func (r LimitResult) Name() string {
+	return r.name
+}
+
+// Algorithm is the name of the algorithm of the limit, see Counter.Algorithm.
+func (r LimitResult) Algorithm() string {
+	return r.alg
+}
+
 // ErrUnexpectedRedisResponse is the error returned when Redis command returns response of unexpected type.
 var ErrUnexpectedRedisResponse = errors.New("counter: unexpected redis response")
 
 // Counter implements distributed counter.
 type Counter struct {
-	client RedisClient
-	script *redis.Script
-	limit  int64
-	size   int
+	store    Store
+	script   *Script
+	alg      int
+	limit    int64
+	size     int
+	calendar *calendar
+	failure  failure
+	observer Observer
 }
 
 // Count increments key value by specified value.
-func (c *Counter) Count(ctx context.Context, key string, value int) (Result, error) {
-	r := Result{}
-	res, err := c.script.Run(ctx, c.client, []string{key}, value, c.size, c.limit).Result()
+func (c *Counter) Count(ctx context.Context, key string, value int) (r Result, err error) {
+	defer c.observer.observe(ctx, "Count", key, time.Now(), &r, &err)
+	k, size := c.window(key)
+	res, err := c.store.Run(ctx, c.script, []string{k}, value, size, c.limit)
+	if err != nil {
+		return c.handle(err, key, func(lt Limiter) (Result, error) {
+			return lt.LimitN(ctx, key, value)
+		})
+	}
+	return newResult(res, c.limit)
+}
+
+//go:embed peek.lua
+var pksrc string
+var pkscr = newScript("peek", pksrc)
+
+// Peek returns current counter value without incrementing.
+// The result is OK if incrementing the counter value by 1 would succeed.
+func (c *Counter) Peek(ctx context.Context, key string) (r Result, err error) {
+	defer c.observer.observe(ctx, "Peek", key, time.Now(), &r, &err)
+	r, err = c.peek(ctx, key, 1)
+	if err != nil {
+		return c.handle(err, key, func(lt Limiter) (Result, error) {
+			return lt.Peek(ctx, key)
+		})
+	}
+	return r, nil
+}
+
+func (c *Counter) peek(ctx context.Context, key string, value int) (Result, error) {
+	key, size := c.window(key)
+	res, err := c.store.Run(ctx, pkscr, []string{key}, value, size, c.limit, c.alg)
 	if err != nil {
-		return r, err
+		return Result{}, err
+	}
+	return newResult(res, c.limit)
+}
+
+//go:embed refund.lua
+var rfsrc string
+var rfscr = newScript("refund", rfsrc)
+
+// Refund decrements key value by specified value, gives back the value previously taken by Count
+// which returned the taken result. The counter value never goes below zero.
+//
+// The counters using fixed window algorithm, and the quotas, give back the value only if the window
+// of the taken result has not ended. The counters using sliding window algorithm give back the value
+// to the window of the taken result while it is the current or the previous window.
+// Otherwise Refund does not change the counter value, so it never gives back the value taken by other callers.
+// The counters using other algorithms do not have windows and give back the value regardless of the taken result.
+// Refund with negative value fails with ErrNegativeN.
+func (c *Counter) Refund(ctx context.Context, key string, taken Result, value int) (r Result, err error) {
+	defer c.observer.observe(ctx, "Refund", key, time.Now(), &r, &err)
+	if value < 0 {
+		return Result{}, ErrNegativeN
+	}
+	r, err = c.refund(ctx, key, taken, value)
+	if err != nil {
+		return c.handle(err, key, func(lt Limiter) (Result, error) {
+			return lt.Refund(ctx, key, taken, value)
+		})
+	}
+	return r, nil
+}
+
+func (c *Counter) refund(ctx context.Context, key string, taken Result, value int) (Result, error) {
+	key, size := c.window(key)
+	res, err := c.store.Run(ctx, rfscr, []string{key}, value, size, c.limit, c.alg, taken.window())
+	if err != nil {
+		return Result{}, err
+	}
+	return newResult(res, c.limit)
+}
+
+//go:embed reset.lua
+var rssrc string
+var rsscr = newScript("reset", rssrc)
+
+// Reset deletes key value, including the keys of the previous windows which are still stored.
+func (c *Counter) Reset(ctx context.Context, key string) (err error) {
+	defer c.observer.observe(ctx, "Reset", key, time.Now(), nil, &err)
+	k, size := c.window(key)
+	_, err = c.store.Run(ctx, rsscr, []string{k}, 0, size, c.limit, c.alg)
+	return err
+}
+
+// Algorithm is the name of the algorithm of the counter: fixedwindow, slidingwindow, tokenbucket, gcra or slidinglog.
+func (c *Counter) Algorithm() string {
+	return c.script.Name()
+}
+
+func (c *Counter) window(key string) (string, int) {
+	if c.calendar != nil {
+		return c.calendar.window(key, time.Now())
 	}
+	return key, c.size
+}
+
+// handle applies the failure policy to the error, the result of the fallback limiter
+// has no results of the limits as every counter result.
+func (c *Counter) handle(err error, key string, fallback func(lt Limiter) (Result, error)) (Result, error) {
+	r, err := c.failure.handle(err, c.degraded(key), fallback)
+	r.limits = nil
+	return r, err
+}
+
+// degraded returns the result of the counter to be returned by the fail closed policy.
+func (c *Counter) degraded(key string) []LimitResult {
+	_, size := c.window(key)
+	return []LimitResult{{Result: Result{ttl: int64(size), limit: c.limit}}}
+}
+
+func (c *Counter) with(options []Option) *Counter {
+	cfg := newConfig(options)
+	c.store = cfg.store(c.store)
+	c.observer = cfg.observer
+	c.failure = cfg.failure.local([]*params{{alg: c.alg, rate: 1, size: c.size, limit: c.limit, calendar: c.calendar}})
+	return c
+}
+
+func newResult(res interface{}, limit int64) (Result, error) {
+	r := Result{}
 	arr, ok := res.([]interface{})
 	if !ok {
 		return r, ErrUnexpectedRedisResponse
@@ -83,24 +240,148 @@ func (c *Counter) Count(ctx context.Context, key string, value int) (Result, err
 	if !ok {
 		return r, ErrUnexpectedRedisResponse
 	}
-	r.limit = c.limit
+	r.limit = limit
+	r.at = time.Now()
 	return r, nil
 }
 
+// newBatchResult creates the result of the limits from the results of each limit, see aggregate.
+func newBatchResult(res interface{}, names []string, args []interface{}) (Result, error) {
+	r := Result{}
+	arr, ok := res.([]interface{})
+	if !ok {
+		return r, ErrUnexpectedRedisResponse
+	}
+	if len(arr) != len(names)*3 {
+		return r, ErrUnexpectedRedisResponse
+	}
+	limits := make([]LimitResult, len(names))
+	for i, name := range names {
+		v, err := newResult(arr[i*3:i*3+3], args[i*4+2].(int64))
+		if err != nil {
+			return r, err
+		}
+		limits[i] = LimitResult{Result: v, name: name, alg: algScript(args[i*4+3].(int)).Name()}
+	}
+	r = aggregate(limits)
+	r.limits = limits
+	return r, nil
+}
+
+// aggregate returns the result with minimal remainder if every limit succeeds,
+// otherwise the result with maximum TTL of the limits which fail.
+func aggregate(limits []LimitResult) Result {
+	var r Result
+	for i, l := range limits {
+		v := l.Result
+		if i == 0 { // first result
+			r = v
+		} else if v.OK() {
+			if r.OK() && r.Remainder() > v.Remainder() { // minimal remainder
+				r = v
+			}
+		} else if r.OK() { // not ok first time
+			r = v
+		} else if r.ttl < v.ttl { // maximum TTL
+			r = v
+		}
+	}
+	return r
+}
+
 //go:embed fixedwindow.lua
 var fwsrc string
-var fwscr = redis.NewScript(fwsrc)
+var fwscr = newScript("fixedwindow", fwsrc)
 
 // FixedWindow creates new counter which implements distributed counter using fixed window algorithm.
-func FixedWindow(client RedisClient, size time.Duration, limit uint) *Counter {
-	return &Counter{client: client, script: fwscr, size: int(size / time.Millisecond), limit: int64(limit)}
+// The size is rounded up to milliseconds, FixedWindow panics if the size is not positive, see WithLimit.
+func FixedWindow(client RedisClient, size time.Duration, limit uint, options ...Option) *Counter {
+	return NewCounter(NewRedisStore(client), WithLimit(size, limit, WithFixedWindow()), options...)
+}
+
+// Quota creates new counter which implements distributed counter using fixed window algorithm
+// with the windows aligned to the calendar periods in the location.
+// The TTL of the result is the time until the end of the current period.
+func Quota(client RedisClient, period Period, loc *time.Location, limit uint, options ...Option) *Counter {
+	return NewCounter(NewRedisStore(client), WithQuota(period, loc, limit), options...)
 }
 
 //go:embed slidingwindow.lua
 var swsrc string
-var swscr = redis.NewScript(swsrc)
+var swscr = newScript("slidingwindow", swsrc)
 
 // SlidingWindow creates new counter which implements distributed counter using sliding window algorithm.
-func SlidingWindow(client RedisClient, size time.Duration, limit uint) *Counter {
-	return &Counter{client: client, script: swscr, size: int(size / time.Millisecond), limit: int64(limit)}
+// The size is rounded up to milliseconds, SlidingWindow panics if the size is not positive, see WithLimit.
+func SlidingWindow(client RedisClient, size time.Duration, limit uint, options ...Option) *Counter {
+	return NewCounter(NewRedisStore(client), WithLimit(size, limit, WithSlidingWindow()), options...)
+}
+
+//go:embed tokenbucket.lua
+var tbsrc string
+var tbscr = newScript("tokenbucket", tbsrc)
+
+// TokenBucket creates new counter which implements distributed counter using token bucket algorithm.
+// The bucket holds at most burst tokens, a token is added to the bucket every rate.
+// The TTL of the result is the time until the bucket is full if the operation succeeds,
+// otherwise the time until the bucket holds enough tokens.
+// The time to refill the whole bucket, rate times burst, is rounded up to milliseconds.
+// TokenBucket panics if rate is not positive or burst is zero.
+func TokenBucket(client RedisClient, rate time.Duration, burst uint, options ...Option) *Counter {
+	if rate <= 0 || burst == 0 {
+		panic(errors.New("counter: token bucket requires positive rate and burst"))
+	}
+	return (&Counter{store: NewRedisStore(client), script: tbscr, alg: algTokenBucket, size: millis(rate * time.Duration(burst)), limit: int64(burst)}).with(options)
+}
+
+// millis converts the duration to milliseconds rounded up.
+func millis(d time.Duration) int {
+	return int((d + time.Millisecond - 1) / time.Millisecond)
+}
+
+//go:embed gcra.lua
+var gcsrc string
+var gcscr = newScript("gcra", gcsrc)
+
+// GCRA creates new counter which implements distributed counter using generic cell rate algorithm.
+// The counter stores only the theoretical arrival time per key, and allows no more than limit within size
+// with the hits spread evenly over size.
+// The TTL of the result is the time until the counter is reset if the operation succeeds,
+// otherwise the time until the operation may succeed.
+// The size is rounded up to milliseconds, GCRA panics if the size is not positive or the limit is zero, see WithLimit.
+func GCRA(client RedisClient, size time.Duration, limit uint, options ...Option) *Counter {
+	return NewCounter(NewRedisStore(client), WithLimit(size, limit, WithGCRA()), options...)
+}
+
+//go:embed slidinglog.lua
+var slsrc string
+var slscr = newScript("slidinglog", slsrc)
+
+// SlidingLog creates new counter which implements distributed counter using sliding log algorithm.
+// The counter stores the timestamp and the value of each hit, so the counter value is exact, at the cost of memory.
+// The TTL of the result is the time until the oldest hit expires.
+// The size is rounded up to milliseconds, SlidingLog panics if the size is not positive, see WithLimit.
+func SlidingLog(client RedisClient, size time.Duration, limit uint, options ...Option) *Counter {
+	return NewCounter(NewRedisStore(client), WithLimit(size, limit, WithSlidingLog()), options...)
+}
+
+// NewCounter creates new counter which implements counter using the store
+// with the algorithm, size and limit of the limit parameters, the name and the rate of the parameters are ignored.
+func NewCounter(store Store, p *params, options ...Option) *Counter {
+	c := &Counter{store: store, script: algScript(p.alg), alg: p.alg, size: p.size, limit: p.limit, calendar: p.calendar}
+	return c.with(options)
+}
+
+// algScript returns the script of the algorithm.
+func algScript(alg int) *Script {
+	switch alg {
+	case algFixed:
+		return fwscr
+	case algSliding:
+		return swscr
+	case algTokenBucket:
+		return tbscr
+	case algGCRA:
+		return gcscr
+	}
+	return slscr
 }
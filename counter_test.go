@@ -36,7 +36,7 @@ func TestCounter(t *testing.T) {
 	clientMock := &ClientMock{}
 	size := 1000
 	limit := int64(100)
-	c := &Counter{client: clientMock, script: fwscr, size: size, limit: limit}
+	c := &Counter{store: NewRedisStore(clientMock), script: fwscr, size: size, limit: limit}
 	ctx := context.Background()
 	hash := fwscr.Hash()
 	value := 1
@@ -87,3 +87,35 @@ func TestCounter(t *testing.T) {
 func msToDuration(ms int64) time.Duration {
 	return time.Duration(ms) * time.Millisecond
 }
+
+func TestCounterAlgorithm(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	require.Equal(t, "fixedwindow", FixedWindow(client, time.Second, 1).Algorithm())
+	require.Equal(t, "fixedwindow", Quota(client, Day, time.UTC, 1).Algorithm())
+	require.Equal(t, "slidingwindow", SlidingWindow(client, time.Second, 1).Algorithm())
+	require.Equal(t, "tokenbucket", TokenBucket(client, time.Second, 1).Algorithm())
+	require.Equal(t, "gcra", GCRA(client, time.Second, 1).Algorithm())
+	require.Equal(t, "slidinglog", SlidingLog(client, time.Second, 1).Algorithm())
+	require.Equal(t, "slidinglog", NewCounter(NewMemory(), WithLimit(time.Second, 1, WithSlidingLog())).Algorithm())
+}
+
+func TestCounterSize(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	size := 1500 * time.Microsecond
+	require.Equal(t, 2, WithLimit(size, 1).size)
+	require.Equal(t, 2, FixedWindow(client, size, 1).size)
+	require.Equal(t, 2, SlidingWindow(client, size, 1).size)
+	require.Equal(t, 2, GCRA(client, size, 1).size)
+	require.Equal(t, 2, SlidingLog(client, size, 1).size)
+
+	require.Panics(t, func() { FixedWindow(client, 0, 1) })
+	require.Panics(t, func() { SlidingWindow(client, -time.Second, 1) })
+	require.Panics(t, func() { GCRA(client, 0, 1) })
+	require.Panics(t, func() { GCRA(client, time.Second, 0) })
+	require.Panics(t, func() { SlidingLog(client, 0, 1) })
+	require.NotPanics(t, func() { FixedWindow(client, time.Second, 0) })
+}
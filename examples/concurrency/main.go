@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/da440dil/go-counter"
+	"github.com/go-redis/redis/v8"
+)
+
+func main() {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	requireNoError(err)
+
+	// No more than 2 operations at the same time, each lease expires after 1 second unless released.
+	limiter := counter.NewConcurrencyLimiter(client, time.Second, 2)
+
+	var mu sync.Mutex
+	run := func() {
+		lease, err := limiter.Acquire(ctx, key)
+		requireNoError(err)
+		mu.Lock()
+		fmt.Printf(
+			"Result: { ok: %v, counter: %v, remainder: %v }\n",
+			lease.OK(), lease.Counter(), lease.Remainder(),
+		)
+		mu.Unlock()
+		if !lease.OK() {
+			return
+		}
+		time.Sleep(100 * time.Millisecond) // do the work
+		err = lease.Release(ctx)
+		requireNoError(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			defer wg.Done()
+			run()
+		}()
+	}
+	wg.Wait()
+	run()
+	// Output:
+	// Result: { ok: true, counter: 1, remainder: 1 }
+	// Result: { ok: true, counter: 2, remainder: 0 }
+	// Result: { ok: false, counter: 2, remainder: 0 }
+	// Result: { ok: true, counter: 1, remainder: 1 }
+}
+
+func requireNoError(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/da440dil/go-counter"
+	"github.com/da440dil/go-counter/httplimit"
+	"github.com/go-redis/redis/v8"
+)
+
+func main() {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, "api:"+key).Err()
+	requireNoError(err)
+
+	// Create middleware which allows no more than 2 requests within 1 second per value of the X-Key header.
+	limit := httplimit.Middleware(
+		counter.NewLimiter(client, counter.WithLimit(time.Second, 2, counter.WithName("api"))),
+		func(r *http.Request) string { return r.Header.Get("X-Key") },
+	)
+	srv := httptest.NewServer(limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	defer srv.Close()
+
+	get := func() {
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		requireNoError(err)
+		req.Header.Set("X-Key", key)
+		res, err := http.DefaultClient.Do(req)
+		requireNoError(err)
+		res.Body.Close()
+		fmt.Printf(
+			"Status: %v, limit: %v, remaining: %v, reset: %v, retry after: %q\n",
+			res.StatusCode, res.Header.Get("RateLimit-Limit"), res.Header.Get("RateLimit-Remaining"),
+			res.Header.Get("RateLimit-Reset"), res.Header.Get("Retry-After"),
+		)
+	}
+	get()
+	get()
+	get()
+	// Output:
+	// Status: 200, limit: 2, remaining: 1, reset: 1, retry after: ""
+	// Status: 200, limit: 2, remaining: 0, reset: 1, retry after: ""
+	// Status: 429, limit: 2, remaining: 0, reset: 1, retry after: "1"
+}
+
+func requireNoError(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,123 @@
+package counter
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	failError = iota
+	failOpen
+	failClosed
+	failFallback
+)
+
+// WithFailOpen makes the limiter or the counter succeed if the store is unavailable.
+//
+// By default the error of the store is returned. With a failure policy the error of the store is returned
+// only if the store replies with an error or an unexpected response, or the context is canceled.
+// Otherwise the operation returns the degraded result instead of the error, the failure policy does not apply to Reset.
+func WithFailOpen() Option {
+	return option(func(c *config) {
+		c.failure = failure{policy: failOpen}
+	})
+}
+
+// WithFailClosed makes the limiter or the counter fail if the store is unavailable,
+// the TTL of the result is the size of the window. See WithFailOpen.
+func WithFailClosed() Option {
+	return option(func(c *config) {
+		c.failure = failure{policy: failClosed}
+	})
+}
+
+// WithFallback makes the limiter or the counter delegate to the fallback limiter if the store is unavailable,
+// returns the degraded result of the same operation of the fallback limiter, for instance Counter.Count calls LimitN
+// with the value of the counter. See WithFailOpen.
+func WithFallback(lt Limiter) Option {
+	return option(func(c *config) {
+		c.failure = failure{policy: failFallback, fallback: lt}
+	})
+}
+
+// WithLocalFallback makes the limiter or the counter delegate to the in-memory limiter with the same limits
+// if the store is unavailable, see WithFallback. The store is used again as soon as it is available.
+//
+// Every limit of the in-memory limiter is the limit divided by the number of the replicas which share the store,
+// at least 1. The number of the replicas less than 1 is treated as 1.
+func WithLocalFallback(replicas uint) Option {
+	if replicas == 0 {
+		replicas = 1
+	}
+	return option(func(c *config) {
+		c.failure = failure{policy: failFallback, replicas: replicas}
+	})
+}
+
+type failure struct {
+	policy   int
+	fallback Limiter
+	replicas uint
+}
+
+// local creates the in-memory fallback limiter with the limits divided by the number of the replicas
+// if the local fallback is set.
+func (f failure) local(limits []*params) failure {
+	if f.replicas == 0 {
+		return f
+	}
+	local := make([]*params, len(limits))
+	rest := make([]Option, len(limits)-1)
+	for i, p := range limits {
+		v := *p
+		v.limit = p.limit / int64(f.replicas)
+		if v.limit < 1 {
+			v.limit = 1
+		}
+		local[i] = &v
+		if i > 0 {
+			rest[i-1] = &v
+		}
+	}
+	f.fallback = NewStoreLimiter(NewMemory(), local[0], rest...)
+	return f
+}
+
+// handle returns the degraded result if the error is caused by unavailable store, otherwise the error.
+// The limits are the results of each limit to be returned by the fail closed policy.
+func (f failure) handle(err error, limits []LimitResult, fallback func(lt Limiter) (Result, error)) (Result, error) {
+	if f.policy == failError || !unavailable(err) {
+		return Result{}, err
+	}
+	if f.policy == failFallback {
+		r, err := fallback(f.fallback)
+		if err != nil {
+			return Result{}, err
+		}
+		r.degraded = true
+		return r, nil
+	}
+	for i := range limits {
+		limits[i].degraded = true
+		if f.policy == failOpen {
+			limits[i].ok = 1
+			limits[i].ttl = 0
+		}
+	}
+	r := aggregate(limits)
+	if len(limits) > 1 {
+		r.limits = limits
+	}
+	return r, nil
+}
+
+// unavailable checks if the error is caused by unavailable store.
+func unavailable(err error) bool {
+	if errors.Is(err, context.Canceled) || err == ErrUnexpectedRedisResponse || err == ErrUnsupportedScript {
+		return false
+	}
+	var rerr redis.Error
+	return !errors.As(err, &rerr)
+}
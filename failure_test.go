@@ -0,0 +1,247 @@
+package counter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnavailable(t *testing.T) {
+	require.True(t, unavailable(errors.New("dial tcp: connection refused")))
+	require.True(t, unavailable(&net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+	require.True(t, unavailable(io.EOF))
+	require.True(t, unavailable(context.DeadlineExceeded))
+	require.False(t, unavailable(context.Canceled))
+	require.False(t, unavailable(redis.Nil))
+	require.False(t, unavailable(ErrUnexpectedRedisResponse))
+	require.False(t, unavailable(ErrUnsupportedScript))
+	require.True(t, unavailable(ErrCircuitOpen))
+	require.True(t, unavailable(ErrTimeout))
+}
+
+func TestCounterFailure(t *testing.T) {
+	ctx := context.Background()
+	e := errors.New("connection refused")
+	store := storeFunc(func(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error) {
+		return nil, e
+	})
+	p := WithLimit(time.Second, 10)
+
+	_, err := NewCounter(store, p).Count(ctx, "key", 1)
+	require.Equal(t, e, err)
+
+	c := NewCounter(store, p, WithFailOpen())
+	result, err := c.Count(ctx, "key", 1)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.True(t, result.Degraded())
+	require.Equal(t, int64(0), result.Counter())
+	require.Equal(t, int64(10), result.Remainder())
+	require.Equal(t, msToDuration(0), result.TTL())
+	require.Nil(t, result.Limits())
+
+	result, err = c.Peek(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.True(t, result.Degraded())
+
+	result, err = c.Refund(ctx, "key", result, 1)
+	require.NoError(t, err)
+	require.True(t, result.Degraded())
+
+	err = c.Reset(ctx, "key")
+	require.Equal(t, e, err)
+
+	c = NewCounter(store, p, WithFailClosed())
+	result, err = c.Count(ctx, "key", 1)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.True(t, result.Degraded())
+	require.Equal(t, int64(10), result.Remainder())
+	require.Equal(t, time.Second, result.TTL())
+
+	memory := NewMemory()
+	c = NewCounter(store, p, WithFallback(NewStoreLimiter(memory, WithLimit(time.Second, 1, WithName("x")))))
+	taken, err := c.Count(ctx, "key", 1)
+	require.NoError(t, err)
+	require.True(t, taken.OK())
+	require.True(t, taken.Degraded())
+	require.Equal(t, int64(1), taken.Counter())
+	require.Nil(t, taken.Limits())
+
+	result, err = c.Count(ctx, "key", 1)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.True(t, result.Degraded())
+
+	result, err = c.Refund(ctx, "key", taken, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), result.Counter())
+	require.Nil(t, result.Limits())
+
+	result, err = c.Peek(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.True(t, result.Degraded())
+
+	e = ErrUnexpectedRedisResponse
+	_, err = c.Count(ctx, "key", 1)
+	require.Equal(t, e, err)
+
+	e = context.Canceled
+	_, err = c.Count(ctx, "key", 1)
+	require.Equal(t, e, err)
+}
+
+func TestLimiterFailure(t *testing.T) {
+	clientMock := &ClientMock{}
+	ctx := context.Background()
+	e := errors.New("connection refused")
+	var i interface{}
+	clientMock.On("EvalSha", ctx, fwscr.Hash(), []string{"x:key"}, 1, 1000, int64(10)).Return(redis.NewCmdResult(i, e))
+
+	_, err := NewLimiter(clientMock, WithLimit(time.Second, 10, WithName("x"))).Limit(ctx, "key")
+	require.Equal(t, e, err)
+
+	result, err := NewLimiter(clientMock, WithLimit(time.Second, 10, WithName("x")), WithFailOpen()).Limit(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.True(t, result.Degraded())
+	require.Len(t, result.Limits(), 1)
+	require.Equal(t, "x", result.Limits()[0].Name())
+	require.True(t, result.Limits()[0].Degraded())
+
+	result, err = NewLimiter(clientMock, WithLimit(time.Second, 10, WithName("x")), WithFailClosed()).Limit(ctx, "key")
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.True(t, result.Degraded())
+	require.Equal(t, time.Second, result.TTL())
+
+	fallback := NewStoreLimiter(NewMemory(), WithLimit(time.Second, 1, WithName("x")))
+	limiter := NewLimiter(clientMock, WithLimit(time.Second, 10, WithName("x")), WithFallback(fallback))
+	result, err = limiter.Limit(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.True(t, result.Degraded())
+	require.Equal(t, int64(1), result.Counter())
+	require.Equal(t, int64(0), result.Remainder())
+
+	result, err = limiter.Limit(ctx, "key")
+	require.NoError(t, err)
+	require.False(t, result.OK())
+
+	clientMock.AssertExpectations(t)
+}
+
+func TestBatchLimiterFailure(t *testing.T) {
+	ctx := context.Background()
+	e := errors.New("connection refused")
+	store := storeFunc(func(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error) {
+		return nil, e
+	})
+	x := WithLimit(time.Second, 10, WithName("x"))
+	y := WithLimit(time.Minute, 5, WithName("y"))
+
+	_, err := NewStoreLimiter(store, x, y).Limit(ctx, "key")
+	require.Equal(t, e, err)
+
+	result, err := NewStoreLimiter(store, x, y, WithFailOpen()).Limit(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.True(t, result.Degraded())
+	require.Equal(t, int64(5), result.Remainder())
+	require.Len(t, result.Limits(), 2)
+	require.Equal(t, "x", result.Limits()[0].Name())
+	require.Equal(t, int64(10), result.Limits()[0].Remainder())
+
+	result, err = NewStoreLimiter(store, x, y, WithFailClosed()).LimitN(ctx, "key", 2)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.True(t, result.Degraded())
+	require.Equal(t, time.Minute, result.TTL())
+	require.Len(t, result.Limits(), 2)
+
+	fallback := NewStoreLimiter(NewMemory(), x, y)
+	limiter := NewStoreLimiter(store, x, y, WithFallback(fallback))
+	taken, err := limiter.LimitN(ctx, "key", 5)
+	require.NoError(t, err)
+	require.True(t, taken.OK())
+	require.True(t, taken.Degraded())
+	require.Equal(t, int64(0), taken.Remainder())
+
+	result, err = limiter.Peek(ctx, "key")
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.True(t, result.Degraded())
+
+	result, err = limiter.Refund(ctx, "key", taken, 5)
+	require.NoError(t, err)
+	require.True(t, result.Degraded())
+	require.Equal(t, int64(5), result.Remainder())
+
+	wctx, cancel := context.WithTimeout(ctx, time.Millisecond*50)
+	defer cancel()
+	_, err = Wait(wctx, NewStoreLimiter(store, WithLimit(time.Millisecond*20, 1), WithFailClosed()), "key")
+	require.Equal(t, ErrWaitDeadline, err)
+}
+
+func TestLocalFallback(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemory()
+	var e error
+	store := storeFunc(func(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error) {
+		if e != nil {
+			return nil, e
+		}
+		return memory.Run(ctx, script, keys, args...)
+	})
+
+	limiter := NewStoreLimiter(store, WithLimit(time.Second, 10, WithName("x")), WithLimit(time.Minute, 2, WithName("y")), WithLocalFallback(3))
+	result, err := limiter.Limit(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.False(t, result.Degraded())
+	require.Equal(t, int64(9), result.Limits()[0].Remainder())
+
+	e = errors.New("connection refused")
+	result, err = limiter.Limit(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.True(t, result.Degraded())
+	require.Equal(t, "x", result.Limits()[0].Name())
+	require.Equal(t, int64(2), result.Limits()[0].Remainder()) // 10 / 3
+	require.Equal(t, int64(0), result.Limits()[1].Remainder()) // at least 1
+
+	result, err = limiter.Limit(ctx, "key")
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.True(t, result.Degraded())
+
+	e = nil
+	result, err = limiter.Limit(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.False(t, result.Degraded())
+	require.Equal(t, int64(8), result.Limits()[0].Remainder())
+
+	c := NewCounter(store, WithLimit(time.Second, 4, WithSlidingWindow()), WithLocalFallback(0))
+	e = errors.New("connection refused")
+	result, err = c.Count(ctx, "key", 4)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.True(t, result.Degraded())
+	require.Equal(t, int64(4), result.Counter())
+	require.Equal(t, int64(0), result.Remainder())
+	require.Nil(t, result.Limits())
+
+	result, err = c.Count(ctx, "key", 1)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.True(t, result.Degraded())
+}
@@ -13,10 +13,15 @@ func TestFixedWindow(t *testing.T) {
 	client := redis.NewClient(&redis.Options{})
 	defer client.Close()
 
+	err := client.Del(context.Background(), "key").Err()
+	require.NoError(t, err)
+
+	testFixedWindow(t, client)
+}
+
+func testFixedWindow(t *testing.T, client RedisClient) {
 	ctx := context.Background()
 	key := "key"
-	err := client.Del(ctx, key).Err()
-	require.NoError(t, err)
 
 	size := time.Second
 	counter := FixedWindow(client, size, 100)
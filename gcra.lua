@@ -0,0 +1,22 @@
+local function gcra(key, value, size, limit)
+	local t = redis.call("time")
+	local now = t[1] * 1000 + math.floor(t[2]/1000)
+	local interval = size / limit
+	local tat = redis.call("get", key)
+	if tat == false then
+		tat = now
+	else
+		tat = math.max(tonumber(tat), now)
+	end
+	local newTat = tat + value * interval
+	local allowAt = newTat - size
+	if allowAt > now then
+		return { 0, math.ceil((tat - now) / interval), math.ceil(allowAt - now) }
+	end
+	local ttl = math.ceil(newTat - now)
+	if ttl > 0 then
+		redis.call("set", key, newTat, "px", ttl)
+	end
+	return { 1, math.ceil((newTat - now) / interval), ttl }
+end
+return gcra(KEYS[1], ARGV[1], ARGV[2], tonumber(ARGV[3]))
\ No newline at end of file
@@ -0,0 +1,60 @@
+package counter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGCRA(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	size := time.Second
+	counter := GCRA(client, size, 100)
+
+	result, err := counter.Count(ctx, key, 101)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.Equal(t, int64(0), result.Counter())
+	require.Equal(t, int64(100), result.Remainder())
+	require.Equal(t, msToDuration(10), result.TTL())
+
+	result, err = counter.Count(ctx, key, 20)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(20), result.Counter())
+	require.Equal(t, int64(80), result.Remainder())
+	require.Equal(t, msToDuration(200), result.TTL())
+
+	result, err = counter.Count(ctx, key, 30)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.True(t, result.Counter() > 45 && result.Counter() <= 50)
+	require.True(t, result.Remainder() >= 50 && result.Remainder() < 55)
+	require.True(t, result.TTL() > msToDuration(450) && result.TTL() <= msToDuration(500))
+
+	result, err = counter.Count(ctx, key, 51)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.True(t, result.Counter() > 45 && result.Counter() <= 50)
+	require.True(t, result.Remainder() >= 50 && result.Remainder() < 55)
+	require.True(t, result.TTL() > msToDuration(0) && result.TTL() <= msToDuration(60))
+
+	time.Sleep(result.TTL() + msToDuration(200)) // wait for 20 hits to be released
+
+	result, err = counter.Count(ctx, key, 70)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.True(t, result.Counter() > 95 && result.Counter() <= 100)
+	require.True(t, result.Remainder() >= 0 && result.Remainder() < 5)
+	require.True(t, result.TTL() > msToDuration(950) && result.TTL() <= size)
+}
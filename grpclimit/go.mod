@@ -0,0 +1,16 @@
+module github.com/da440dil/go-counter/grpclimit
+
+go 1.16
+
+// The replace directive is for the development in this repository only,
+// the released module requires the tagged release of github.com/da440dil/go-counter.
+replace github.com/da440dil/go-counter => ../
+
+require (
+	github.com/da440dil/go-counter v0.6.0
+	github.com/go-redis/redis/v8 v8.11.5
+	github.com/stretchr/testify v1.7.1
+	google.golang.org/genproto v0.0.0-20200526211855-cb27e3aa2013
+	google.golang.org/grpc v1.43.0
+	google.golang.org/protobuf v1.26.0
+)
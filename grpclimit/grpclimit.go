@@ -0,0 +1,166 @@
+// Package grpclimit provides gRPC server interceptors for distributed rate limiting.
+package grpclimit
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/da440dil/go-counter"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// KeyFunc returns the key to limit the call by, the call is not limited if the key is empty.
+type KeyFunc func(ctx context.Context, fullMethod string) string
+
+// Metadata creates key function which returns the first value of the incoming metadata with the name.
+func Metadata(name string) KeyFunc {
+	prefix := "metadata:" + strings.ToLower(name) + ":"
+	return func(ctx context.Context, fullMethod string) string {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return ""
+		}
+		if v := md.Get(name); len(v) != 0 && v[0] != "" {
+			return prefix + v[0]
+		}
+		return ""
+	}
+}
+
+// Peer creates key function which returns the IP address of the peer.
+//
+// IPv6 addresses are grouped by the network with the prefix length, for instance 64,
+// the whole address is used if the prefix length is 0. The peer without IP address,
+// for instance connected with Unix socket, is limited by its address.
+func Peer(ipv6PrefixLen int) KeyFunc {
+	return func(ctx context.Context, fullMethod string) string {
+		p, ok := peer.FromContext(ctx)
+		if !ok || p.Addr == nil {
+			return ""
+		}
+		addr := p.Addr.String()
+		host := addr
+		if h, _, err := net.SplitHostPort(addr); err == nil {
+			host = h
+		}
+		if i := strings.IndexByte(host, '%'); i != -1 { // zone
+			host = host[:i]
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return "peer:" + addr
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return "ip:" + ip4.String()
+		}
+		if ipv6PrefixLen > 0 && ipv6PrefixLen < 128 {
+			ip = ip.Mask(net.CIDRMask(ipv6PrefixLen, 128))
+		}
+		return "ip:" + ip.String()
+	}
+}
+
+// FullMethod creates key function which returns the full method name of the call.
+func FullMethod() KeyFunc {
+	return func(ctx context.Context, fullMethod string) string {
+		return "method:" + fullMethod
+	}
+}
+
+// ErrorHandler handles the error of the limiter, returns the error to fail the call with,
+// or nil to let the call proceed without the limit.
+type ErrorHandler func(ctx context.Context, fullMethod string, err error) error
+
+type params struct {
+	onError ErrorHandler
+}
+
+// WithErrorHandler sets the handler of the limiter errors, by default the call fails with the Internal status
+// without the details of the error.
+func WithErrorHandler(h ErrorHandler) func(*params) {
+	return func(p *params) {
+		p.onError = h
+	}
+}
+
+func newParams(options []func(*params)) *params {
+	p := &params{onError: internalError}
+	for _, opt := range options {
+		opt(p)
+	}
+	return p
+}
+
+func internalError(ctx context.Context, fullMethod string, err error) error {
+	return status.Error(codes.Internal, "rate limit unavailable")
+}
+
+// UnaryServerInterceptor creates interceptor which applies the limit to every unary call
+// with the key returned by the key function.
+//
+// Every limited call has the ratelimit-limit, ratelimit-remaining and ratelimit-reset header metadata.
+// The denied call fails with the ResourceExhausted status with the retry info in the details,
+// the limiter error is handled by the error handler.
+func UnaryServerInterceptor(lt counter.Limiter, key KeyFunc, options ...func(*params)) grpc.UnaryServerInterceptor {
+	p := newParams(options)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := p.limit(ctx, lt, key(ctx, info.FullMethod), info.FullMethod, func(md metadata.MD) error {
+			return grpc.SetHeader(ctx, md)
+		}); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor creates interceptor which applies the limit to every stream
+// with the key returned by the key function, see UnaryServerInterceptor.
+func StreamServerInterceptor(lt counter.Limiter, key KeyFunc, options ...func(*params)) grpc.StreamServerInterceptor {
+	p := newParams(options)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		if err := p.limit(ctx, lt, key(ctx, info.FullMethod), info.FullMethod, ss.SetHeader); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (p *params) limit(ctx context.Context, lt counter.Limiter, key, fullMethod string, setHeader func(metadata.MD) error) error {
+	if key == "" {
+		return nil
+	}
+	result, err := lt.Limit(ctx, key)
+	if err != nil {
+		return p.onError(ctx, fullMethod, err)
+	}
+	remainder := result.Remainder()
+	if remainder < 0 {
+		remainder = 0
+	}
+	if err = setHeader(metadata.Pairs(
+		"ratelimit-limit", strconv.FormatInt(result.Counter()+result.Remainder(), 10),
+		"ratelimit-remaining", strconv.FormatInt(remainder, 10),
+		"ratelimit-reset", strconv.FormatInt(int64((result.TTL()+time.Second-1)/time.Second), 10),
+	)); err != nil {
+		return err
+	}
+	if result.OK() {
+		return nil
+	}
+	st, err := status.New(codes.ResourceExhausted, "rate limit exceeded").
+		WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(result.TTL())})
+	if err != nil {
+		return err
+	}
+	return st.Err()
+}
@@ -0,0 +1,184 @@
+package grpclimit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/da440dil/go-counter"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type ClientMock struct {
+	mock.Mock
+}
+
+func (m *ClientMock) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	arg := m.Called(append([]interface{}{ctx, sha1, keys}, args...)...)
+	return arg.Get(0).(*redis.Cmd)
+}
+
+func (m *ClientMock) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	return nil
+}
+
+func (m *ClientMock) ScriptExists(ctx context.Context, hashes ...string) *redis.BoolSliceCmd {
+	return nil
+}
+
+func (m *ClientMock) ScriptLoad(ctx context.Context, script string) *redis.StringCmd {
+	return nil
+}
+
+type storeFunc func(ctx context.Context, script *counter.Script, keys []string, args ...interface{}) (interface{}, error)
+
+func (fn storeFunc) Run(ctx context.Context, script *counter.Script, keys []string, args ...interface{}) (interface{}, error) {
+	return fn(ctx, script, keys, args...)
+}
+
+func dial(t *testing.T, lt counter.Limiter, key KeyFunc, options ...func(*params)) grpc_health_v1.HealthClient {
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(lt, key, options...)),
+		grpc.StreamInterceptor(StreamServerInterceptor(lt, key, options...)),
+	)
+	grpc_health_v1.RegisterHealthServer(srv, health.NewServer())
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial(
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithInsecure(),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-key", key)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	clientMock := &ClientMock{}
+	lt := counter.NewLimiter(clientMock, counter.WithLimit(time.Second*2, 2, counter.WithName("x")))
+	client := dial(t, lt, Metadata("x-key"))
+
+	clientMock.On("EvalSha", mock.Anything, mock.Anything, []string{"x:metadata:x-key:1"}, 1, mock.Anything, mock.Anything).
+		Return(redis.NewCmdResult([]interface{}{int64(1), int64(1), int64(2000)}, nil))
+	var header metadata.MD
+	_, err := client.Check(withKey("1"), &grpc_health_v1.HealthCheckRequest{}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Equal(t, []string{"2"}, header.Get("ratelimit-limit"))
+	require.Equal(t, []string{"1"}, header.Get("ratelimit-remaining"))
+	require.Equal(t, []string{"2"}, header.Get("ratelimit-reset"))
+
+	clientMock.On("EvalSha", mock.Anything, mock.Anything, []string{"x:metadata:x-key:2"}, 1, mock.Anything, mock.Anything).
+		Return(redis.NewCmdResult([]interface{}{int64(0), int64(2), int64(1500)}, nil))
+	_, err = client.Check(withKey("2"), &grpc_health_v1.HealthCheckRequest{}, grpc.Header(&header))
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, []string{"0"}, header.Get("ratelimit-remaining"))
+	details := status.Convert(err).Details()
+	require.Len(t, details, 1)
+	info, ok := details[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	require.Equal(t, time.Millisecond*1500, info.RetryDelay.AsDuration())
+
+	e := errors.New("redis error")
+	clientMock.On("EvalSha", mock.Anything, mock.Anything, []string{"x:metadata:x-key:3"}, 1, mock.Anything, mock.Anything).
+		Return(redis.NewCmdResult(nil, e))
+	_, err = client.Check(withKey("3"), &grpc_health_v1.HealthCheckRequest{})
+	require.Equal(t, codes.Internal, status.Code(err))
+	require.NotContains(t, status.Convert(err).Message(), e.Error())
+
+	_, err = client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+
+	clientMock.AssertExpectations(t)
+}
+
+func TestErrorHandler(t *testing.T) {
+	e := errors.New("dial tcp 10.0.0.1:6379: connection refused")
+	store := storeFunc(func(ctx context.Context, script *counter.Script, keys []string, args ...interface{}) (interface{}, error) {
+		return nil, e
+	})
+	lt := counter.NewStoreLimiter(store, counter.WithLimit(time.Second, 1))
+	var errs []error
+	client := dial(t, lt, Metadata("x-key"), WithErrorHandler(func(ctx context.Context, fullMethod string, err error) error {
+		require.Equal(t, "/grpc.health.v1.Health/Check", fullMethod)
+		errs = append(errs, err)
+		return nil
+	}))
+
+	_, err := client.Check(withKey("1"), &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []error{e}, errs)
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	clientMock := &ClientMock{}
+	lt := counter.NewLimiter(clientMock, counter.WithLimit(time.Second, 1, counter.WithName("x")))
+	client := dial(t, lt, Metadata("x-key"))
+
+	clientMock.On("EvalSha", mock.Anything, mock.Anything, []string{"x:metadata:x-key:1"}, 1, mock.Anything, mock.Anything).
+		Return(redis.NewCmdResult([]interface{}{int64(1), int64(1), int64(1000)}, nil))
+	stream, err := client.Watch(withKey("1"), &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+	header, err := stream.Header()
+	require.NoError(t, err)
+	require.Equal(t, []string{"0"}, header.Get("ratelimit-remaining"))
+
+	clientMock.On("EvalSha", mock.Anything, mock.Anything, []string{"x:metadata:x-key:2"}, 1, mock.Anything, mock.Anything).
+		Return(redis.NewCmdResult([]interface{}{int64(0), int64(1), int64(200)}, nil))
+	stream, err = client.Watch(withKey("2"), &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	clientMock.AssertExpectations(t)
+}
+
+func TestKeyFunc(t *testing.T) {
+	ctx := context.Background()
+	method := "/grpc.health.v1.Health/Check"
+
+	require.Equal(t, "", Metadata("x-key")(ctx, method))
+	md := metadata.Pairs("x-key", "a", "x-key", "b")
+	require.Equal(t, "metadata:x-key:a", Metadata("X-Key")(metadata.NewIncomingContext(ctx, md), method))
+
+	require.Equal(t, "", Peer(64)(ctx, method))
+	tests := map[string]net.Addr{
+		"ip:192.0.2.1":        &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 8080},
+		"ip:2001:db8:1:2::":   &net.TCPAddr{IP: net.ParseIP("2001:db8:1:2:3:4:5:6"), Port: 8080, Zone: "eth0"},
+		"peer:/tmp/grpc.sock": &net.UnixAddr{Name: "/tmp/grpc.sock", Net: "unix"},
+	}
+	for key, addr := range tests {
+		require.Equal(t, key, Peer(64)(peer.NewContext(ctx, &peer.Peer{Addr: addr}), method))
+	}
+	addr := &net.TCPAddr{IP: net.ParseIP("2001:db8:1:2:3:4:5:6"), Port: 8080}
+	require.Equal(t, "ip:2001:db8:1:2:3:4:5:6", Peer(0)(peer.NewContext(ctx, &peer.Peer{Addr: addr}), method))
+
+	require.Equal(t, "method:"+method, FullMethod()(ctx, method))
+}
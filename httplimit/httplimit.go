@@ -0,0 +1,94 @@
+// Package httplimit provides net/http middleware for distributed rate limiting.
+package httplimit
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/da440dil/go-counter"
+)
+
+// KeyFunc returns the key to limit the request by, the request is not limited if the key is empty.
+type KeyFunc func(r *http.Request) string
+
+// ErrorHandler handles the error of the limiter.
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+type params struct {
+	onError  ErrorHandler
+	onDenied http.Handler
+}
+
+// WithErrorHandler sets the handler of the limiter errors,
+// by default the middleware responds with status 500.
+func WithErrorHandler(h ErrorHandler) func(*params) {
+	return func(p *params) {
+		p.onError = h
+	}
+}
+
+// WithDeniedHandler sets the handler of the denied requests,
+// by default the middleware responds with status 429.
+// The rate limit headers and the Retry-After header are set before the handler is called.
+func WithDeniedHandler(h http.Handler) func(*params) {
+	return func(p *params) {
+		p.onDenied = h
+	}
+}
+
+// Middleware creates middleware which applies the limit to every request with the key returned by the key function.
+//
+// Every limited response has the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers,
+// the denied response has the Retry-After header in addition.
+func Middleware(lt counter.Limiter, key KeyFunc, options ...func(*params)) func(http.Handler) http.Handler {
+	p := &params{onError: internalError, onDenied: http.HandlerFunc(tooManyRequests)}
+	for _, opt := range options {
+		opt(p)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			result, err := lt.Limit(r.Context(), k)
+			if err != nil {
+				p.onError(w, r, err)
+				return
+			}
+			SetHeaders(w.Header(), result)
+			if !result.OK() {
+				w.Header().Set("Retry-After", seconds(result.TTL()))
+				p.onDenied.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SetHeaders sets the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers from the result.
+func SetHeaders(h http.Header, result counter.Result) {
+	remainder := result.Remainder()
+	if remainder < 0 {
+		remainder = 0
+	}
+	h.Set("RateLimit-Limit", strconv.FormatInt(result.Counter()+result.Remainder(), 10))
+	h.Set("RateLimit-Remaining", strconv.FormatInt(remainder, 10))
+	h.Set("RateLimit-Reset", seconds(result.TTL()))
+}
+
+// seconds formats the duration as the number of seconds rounded up.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
+
+func internalError(w http.ResponseWriter, r *http.Request, err error) {
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+func tooManyRequests(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+}
@@ -0,0 +1,150 @@
+package httplimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/da440dil/go-counter"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type ClientMock struct {
+	mock.Mock
+}
+
+func (m *ClientMock) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	arg := m.Called(append([]interface{}{ctx, sha1, keys}, args...)...)
+	return arg.Get(0).(*redis.Cmd)
+}
+
+func (m *ClientMock) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	return nil
+}
+
+func (m *ClientMock) ScriptExists(ctx context.Context, hashes ...string) *redis.BoolSliceCmd {
+	return nil
+}
+
+func (m *ClientMock) ScriptLoad(ctx context.Context, script string) *redis.StringCmd {
+	return nil
+}
+
+type LimiterMock struct {
+	mock.Mock
+}
+
+func (m *LimiterMock) Limit(ctx context.Context, key string) (counter.Result, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(counter.Result), args.Error(1)
+}
+
+func (m *LimiterMock) LimitN(ctx context.Context, key string, n int) (counter.Result, error) {
+	args := m.Called(ctx, key, n)
+	return args.Get(0).(counter.Result), args.Error(1)
+}
+
+func (m *LimiterMock) Peek(ctx context.Context, key string) (counter.Result, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(counter.Result), args.Error(1)
+}
+
+func (m *LimiterMock) Refund(ctx context.Context, key string, taken counter.Result, n int) (counter.Result, error) {
+	args := m.Called(ctx, key, taken, n)
+	return args.Get(0).(counter.Result), args.Error(1)
+}
+
+func (m *LimiterMock) Reset(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func byHeader(r *http.Request) string {
+	return r.Header.Get("X-Key")
+}
+
+func serve(h http.Handler, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if key != "" {
+		req.Header.Set("X-Key", key)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	clientMock := &ClientMock{}
+	lt := counter.NewLimiter(clientMock, counter.WithLimit(time.Second*2, 2, counter.WithName("x")))
+	h := Middleware(lt, byHeader)(ok)
+
+	clientMock.On("EvalSha", mock.Anything, mock.Anything, []string{"x:1"}, 1, mock.Anything, mock.Anything).
+		Return(redis.NewCmdResult([]interface{}{int64(1), int64(1), int64(2000)}, nil))
+	rec := serve(h, "1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "2", rec.Header().Get("RateLimit-Reset"))
+	require.Equal(t, "", rec.Header().Get("Retry-After"))
+
+	clientMock.On("EvalSha", mock.Anything, mock.Anything, []string{"x:2"}, 1, mock.Anything, mock.Anything).
+		Return(redis.NewCmdResult([]interface{}{int64(0), int64(2), int64(1001)}, nil))
+	rec = serve(h, "2")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "2", rec.Header().Get("RateLimit-Reset"))
+	require.Equal(t, "2", rec.Header().Get("Retry-After"))
+
+	rec = serve(h, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "", rec.Header().Get("RateLimit-Limit"))
+
+	clientMock.AssertExpectations(t)
+}
+
+func TestMiddlewareDeniedHandler(t *testing.T) {
+	clientMock := &ClientMock{}
+	lt := counter.NewLimiter(clientMock, counter.WithLimit(time.Second, 1, counter.WithName("x")))
+	denied := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	h := Middleware(lt, byHeader, WithDeniedHandler(denied))(ok)
+
+	clientMock.On("EvalSha", mock.Anything, mock.Anything, []string{"x:1"}, 1, mock.Anything, mock.Anything).
+		Return(redis.NewCmdResult([]interface{}{int64(0), int64(1), int64(500)}, nil))
+	rec := serve(h, "1")
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	clientMock.AssertExpectations(t)
+}
+
+func TestMiddlewareError(t *testing.T) {
+	lt := &LimiterMock{}
+	e := errors.New("redis error")
+	lt.On("Limit", mock.Anything, "key").Return(counter.Result{}, e)
+
+	rec := serve(Middleware(lt, byHeader)(ok), "key")
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	var herr error
+	onError := func(w http.ResponseWriter, r *http.Request, err error) {
+		herr = err
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	rec = serve(Middleware(lt, byHeader, WithErrorHandler(onError))(ok), "key")
+	require.Equal(t, http.StatusBadGateway, rec.Code)
+	require.Equal(t, e, herr)
+
+	lt.AssertExpectations(t)
+}
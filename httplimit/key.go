@@ -0,0 +1,163 @@
+package httplimit
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RemoteIP creates key function which returns the IP address of the request remote address.
+//
+// IPv6 addresses are grouped by the network with the prefix length, for instance 64,
+// the whole address is used if the prefix length is 0.
+func RemoteIP(ipv6PrefixLen int) KeyFunc {
+	return func(r *http.Request) string {
+		ip := parseIP(r.RemoteAddr)
+		if ip == nil {
+			return ""
+		}
+		return ipKey(ip, ipv6PrefixLen)
+	}
+}
+
+// XForwardedFor creates key function which returns the IP address of the client
+// from the X-Forwarded-For header of the request, see Forwarded.
+func XForwardedFor(trusted []*net.IPNet, ipv6PrefixLen int) KeyFunc {
+	return forwarded(trusted, ipv6PrefixLen, func(r *http.Request) []string {
+		var hops []string
+		for _, v := range r.Header.Values("X-Forwarded-For") {
+			hops = append(hops, strings.Split(v, ",")...)
+		}
+		return hops
+	})
+}
+
+// Forwarded creates key function which returns the IP address of the client
+// from the "for" parameters of the Forwarded header of the request.
+//
+// The header is used only if the request remote address belongs to the trusted proxies,
+// the addresses of the header are walked from right to left skipping the addresses of the trusted proxies,
+// so the client is the first address which does not belong to the trusted proxies.
+// The addresses which are set by the client itself are never reached unless every proxy is trusted.
+// IPv6 addresses are grouped by the network with the prefix length, see RemoteIP.
+func Forwarded(trusted []*net.IPNet, ipv6PrefixLen int) KeyFunc {
+	return forwarded(trusted, ipv6PrefixLen, func(r *http.Request) []string {
+		var hops []string
+		for _, v := range r.Header.Values("Forwarded") {
+			for _, elem := range strings.Split(v, ",") {
+				hop := ""
+				for _, pair := range strings.Split(elem, ";") {
+					i := strings.IndexByte(pair, '=')
+					if i != -1 && strings.EqualFold(strings.TrimSpace(pair[:i]), "for") {
+						hop = strings.Trim(strings.TrimSpace(pair[i+1:]), `"`)
+					}
+				}
+				hops = append(hops, hop)
+			}
+		}
+		return hops
+	})
+}
+
+func forwarded(trusted []*net.IPNet, ipv6PrefixLen int, hops func(r *http.Request) []string) KeyFunc {
+	isTrusted := func(ip net.IP) bool {
+		for _, n := range trusted {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(r *http.Request) string {
+		ip := parseIP(r.RemoteAddr)
+		if ip == nil {
+			return ""
+		}
+		if !isTrusted(ip) {
+			return ipKey(ip, ipv6PrefixLen)
+		}
+		arr := hops(r)
+		for i := len(arr) - 1; i >= 0; i-- {
+			hop := parseIP(strings.TrimSpace(arr[i]))
+			if hop == nil { // the address of the nearest proxy is the only one which can be trusted
+				break
+			}
+			ip = hop
+			if !isTrusted(ip) {
+				break
+			}
+		}
+		return ipKey(ip, ipv6PrefixLen)
+	}
+}
+
+// Header creates key function which returns the value of the request header with the name,
+// for instance the API key.
+func Header(name string) KeyFunc {
+	prefix := "header:" + http.CanonicalHeaderKey(name) + ":"
+	return func(r *http.Request) string {
+		if v := r.Header.Get(name); v != "" {
+			return prefix + v
+		}
+		return ""
+	}
+}
+
+// Query creates key function which returns the value of the request query parameter with the name.
+func Query(name string) KeyFunc {
+	prefix := "query:" + name + ":"
+	return func(r *http.Request) string {
+		if v := r.URL.Query().Get(name); v != "" {
+			return prefix + v
+		}
+		return ""
+	}
+}
+
+// First creates key function which returns the first not empty key of the key functions.
+func First(fns ...KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		for _, fn := range fns {
+			if k := fn(r); k != "" {
+				return k
+			}
+		}
+		return ""
+	}
+}
+
+// ParseCIDRs parses the CIDR notation IP addresses and prefix lengths of the trusted proxies.
+func ParseCIDRs(cidrs ...string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, s := range cidrs {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		nets[i] = n
+	}
+	return nets, nil
+}
+
+// parseIP parses the IP address with optional port, IPv6 address may be in square brackets.
+func parseIP(s string) net.IP {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	} else if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		s = s[1 : len(s)-1]
+	}
+	if i := strings.IndexByte(s, '%'); i != -1 { // zone
+		s = s[:i]
+	}
+	return net.ParseIP(s)
+}
+
+func ipKey(ip net.IP, ipv6PrefixLen int) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return "ip:" + ip4.String()
+	}
+	if ipv6PrefixLen > 0 && ipv6PrefixLen < 128 {
+		ip = ip.Mask(net.CIDRMask(ipv6PrefixLen, 128))
+	}
+	return "ip:" + ip.String()
+}
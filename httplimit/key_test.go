@@ -0,0 +1,127 @@
+package httplimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteIP(t *testing.T) {
+	tests := []struct {
+		remoteAddr    string
+		ipv6PrefixLen int
+		key           string
+	}{
+		{"192.0.2.1:1234", 64, "ip:192.0.2.1"},
+		{"[::ffff:192.0.2.1]:1234", 64, "ip:192.0.2.1"},
+		{"[2001:db8:1:2:3:4:5:6]:1234", 64, "ip:2001:db8:1:2::"},
+		{"[2001:db8:1:2:3:4:5:6]:1234", 48, "ip:2001:db8:1::"},
+		{"[2001:db8:1:2:3:4:5:6]:1234", 0, "ip:2001:db8:1:2:3:4:5:6"},
+		{"[fe80::1%eth0]:1234", 0, "ip:fe80::1"},
+		{"192.0.2.1", 64, "ip:192.0.2.1"},
+		{"invalid", 64, ""},
+		{"", 64, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.remoteAddr, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tc.remoteAddr
+			require.Equal(t, tc.key, RemoteIP(tc.ipv6PrefixLen)(r))
+		})
+	}
+}
+
+func TestXForwardedFor(t *testing.T) {
+	trusted, err := ParseCIDRs("10.0.0.0/8", "2001:db8:ffff::/48")
+	require.NoError(t, err)
+	fn := XForwardedFor(trusted, 64)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    []string
+		key        string
+	}{
+		{"untrusted remote", "192.0.2.1:1234", []string{"198.51.100.1"}, "ip:192.0.2.1"},
+		{"no header", "10.0.0.1:1234", nil, "ip:10.0.0.1"},
+		{"single hop", "10.0.0.1:1234", []string{"198.51.100.1"}, "ip:198.51.100.1"},
+		{"spoofed", "10.0.0.1:1234", []string{"203.0.113.1, 198.51.100.1"}, "ip:198.51.100.1"},
+		{"trusted hops", "10.0.0.1:1234", []string{"203.0.113.1, 198.51.100.1, 10.0.0.2"}, "ip:198.51.100.1"},
+		{"multiple headers", "10.0.0.1:1234", []string{"203.0.113.1", "198.51.100.1, 10.0.0.2"}, "ip:198.51.100.1"},
+		{"every hop trusted", "10.0.0.1:1234", []string{"10.0.0.3, 10.0.0.2"}, "ip:10.0.0.3"},
+		{"invalid hop", "10.0.0.1:1234", []string{"198.51.100.1, invalid, 10.0.0.2"}, "ip:10.0.0.2"},
+		{"ipv6 hop", "[2001:db8:ffff::1]:1234", []string{"2001:db8:1:2:3:4:5:6"}, "ip:2001:db8:1:2::"},
+		{"invalid remote", "invalid", []string{"198.51.100.1"}, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tc.remoteAddr
+			for _, h := range tc.headers {
+				r.Header.Add("X-Forwarded-For", h)
+			}
+			require.Equal(t, tc.key, fn(r))
+		})
+	}
+}
+
+func TestForwarded(t *testing.T) {
+	trusted, err := ParseCIDRs("10.0.0.0/8")
+	require.NoError(t, err)
+	fn := Forwarded(trusted, 64)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    []string
+		key        string
+	}{
+		{"untrusted remote", "192.0.2.1:1234", []string{"for=198.51.100.1"}, "ip:192.0.2.1"},
+		{"single hop", "10.0.0.1:1234", []string{"for=198.51.100.1;proto=https"}, "ip:198.51.100.1"},
+		{"spoofed", "10.0.0.1:1234", []string{"for=203.0.113.1, for=198.51.100.1"}, "ip:198.51.100.1"},
+		{"trusted hops", "10.0.0.1:1234", []string{"for=198.51.100.1, By=10.0.0.1;For=10.0.0.2"}, "ip:198.51.100.1"},
+		{"quoted ipv6", "10.0.0.1:1234", []string{`for="[2001:db8:1:2:3:4:5:6]:4711"`}, "ip:2001:db8:1:2::"},
+		{"quoted ipv4 with port", "10.0.0.1:1234", []string{`for="198.51.100.1:4711"`}, "ip:198.51.100.1"},
+		{"obfuscated", "10.0.0.1:1234", []string{"for=198.51.100.1, for=_hidden, for=10.0.0.2"}, "ip:10.0.0.2"},
+		{"unknown", "10.0.0.1:1234", []string{"for=unknown"}, "ip:10.0.0.1"},
+		{"missing for", "10.0.0.1:1234", []string{"proto=https"}, "ip:10.0.0.1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tc.remoteAddr
+			for _, h := range tc.headers {
+				r.Header.Add("Forwarded", h)
+			}
+			require.Equal(t, tc.key, fn(r))
+		})
+	}
+}
+
+func TestFirst(t *testing.T) {
+	fn := First(Header("x-api-key"), Query("api_key"), RemoteIP(64))
+
+	r := httptest.NewRequest(http.MethodGet, "/?api_key=q", nil)
+	r.Header.Set("X-Api-Key", "h")
+	require.Equal(t, "header:X-Api-Key:h", fn(r))
+
+	r = httptest.NewRequest(http.MethodGet, "/?api_key=q", nil)
+	require.Equal(t, "query:api_key:q", fn(r))
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	require.Equal(t, "ip:192.0.2.1", fn(r))
+
+	r.RemoteAddr = ""
+	require.Equal(t, "", fn(r))
+}
+
+func TestParseCIDRs(t *testing.T) {
+	nets, err := ParseCIDRs("10.0.0.0/8", "::1/128")
+	require.NoError(t, err)
+	require.Len(t, nets, 2)
+
+	_, err = ParseCIDRs("10.0.0.0/8", "10.0.0.1")
+	require.Error(t, err)
+}
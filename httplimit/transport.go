@@ -0,0 +1,100 @@
+package httplimit
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/da440dil/go-counter"
+)
+
+// LimitError is the error returned by the transport when the limit is exceeded.
+type LimitError struct {
+	counter.Result
+	err error
+}
+
+func (e *LimitError) Error() string {
+	if e.err != nil {
+		return "httplimit: rate limit exceeded: " + e.err.Error()
+	}
+	return fmt.Sprintf("httplimit: rate limit exceeded, retry after %v", e.TTL())
+}
+
+// Unwrap returns counter.ErrExceedsLimit or counter.ErrWaitDeadline if the transport fails to wait, otherwise nil.
+func (e *LimitError) Unwrap() error {
+	return e.err
+}
+
+// Host creates key function which returns the host of the request URL.
+func Host() KeyFunc {
+	return func(r *http.Request) string {
+		if r.URL.Host != "" {
+			return "host:" + r.URL.Host
+		}
+		return ""
+	}
+}
+
+type transportParams struct {
+	wait bool
+}
+
+// WithWait makes the transport wait until the limit allows the request instead of failing fast, see counter.Wait.
+func WithWait() func(*transportParams) {
+	return func(p *transportParams) {
+		p.wait = true
+	}
+}
+
+// NewTransport creates transport which applies the limit to every outbound request
+// with the key returned by the key function before sending the request with the base transport.
+//
+// By default the transport fails fast with *LimitError if the limit is exceeded, may be set with options.
+// If base is nil, http.DefaultTransport is used.
+func NewTransport(base http.RoundTripper, lt counter.Limiter, key KeyFunc, options ...func(*transportParams)) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	p := &transportParams{}
+	for _, opt := range options {
+		opt(p)
+	}
+	return &transport{base: base, limiter: lt, key: key, wait: p.wait}
+}
+
+type transport struct {
+	base    http.RoundTripper
+	limiter counter.Limiter
+	key     KeyFunc
+	wait    bool
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if k := t.key(r); k != "" {
+		if err := t.limit(r, k); err != nil {
+			if r.Body != nil {
+				r.Body.Close()
+			}
+			return nil, err
+		}
+	}
+	return t.base.RoundTrip(r)
+}
+
+func (t *transport) limit(r *http.Request, key string) error {
+	if t.wait {
+		result, err := counter.Wait(r.Context(), t.limiter, key)
+		if err == counter.ErrExceedsLimit || err == counter.ErrWaitDeadline {
+			return &LimitError{Result: result, err: err}
+		}
+		return err
+	}
+	result, err := t.limiter.Limit(r.Context(), key)
+	if err != nil {
+		return err
+	}
+	if !result.OK() {
+		return &LimitError{Result: result}
+	}
+	return nil
+}
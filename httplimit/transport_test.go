@@ -0,0 +1,101 @@
+package httplimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/da440dil/go-counter"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+func TestTransport(t *testing.T) {
+	clientMock := &ClientMock{}
+	lt := counter.NewLimiter(clientMock, counter.WithLimit(time.Second, 1, counter.WithName("x")))
+	calls := 0
+	base := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})
+	client := &http.Client{Transport: NewTransport(base, lt, Host())}
+
+	clientMock.On("EvalSha", mock.Anything, mock.Anything, []string{"x:host:a.test"}, 1, mock.Anything, mock.Anything).
+		Return(redis.NewCmdResult([]interface{}{int64(1), int64(1), int64(1000)}, nil))
+	res, err := client.Get("http://a.test/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, 1, calls)
+
+	clientMock.On("EvalSha", mock.Anything, mock.Anything, []string{"x:host:b.test"}, 1, mock.Anything, mock.Anything).
+		Return(redis.NewCmdResult([]interface{}{int64(0), int64(1), int64(500)}, nil))
+	_, err = client.Get("http://b.test/")
+	var lerr *LimitError
+	require.True(t, errors.As(err, &lerr))
+	require.False(t, lerr.OK())
+	require.Equal(t, time.Millisecond*500, lerr.TTL())
+	require.Equal(t, "httplimit: rate limit exceeded, retry after 500ms", lerr.Error())
+	require.Equal(t, 1, calls)
+
+	e := errors.New("redis error")
+	clientMock.On("EvalSha", mock.Anything, mock.Anything, []string{"x:host:c.test"}, 1, mock.Anything, mock.Anything).
+		Return(redis.NewCmdResult(nil, e))
+	_, err = client.Get("http://c.test/")
+	require.True(t, errors.Is(err, e))
+	require.Equal(t, 1, calls)
+
+	clientMock.AssertExpectations(t)
+}
+
+func TestTransportWait(t *testing.T) {
+	clientMock := &ClientMock{}
+	lt := counter.NewLimiter(clientMock, counter.WithLimit(time.Second, 1, counter.WithName("x")))
+	calls := 0
+	base := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})
+	client := &http.Client{Transport: NewTransport(base, lt, Host(), WithWait())}
+
+	clientMock.On("EvalSha", mock.Anything, mock.Anything, []string{"x:host:a.test"}, 1, mock.Anything, mock.Anything).
+		Return(redis.NewCmdResult([]interface{}{int64(0), int64(1), int64(20)}, nil)).Once()
+	clientMock.On("EvalSha", mock.Anything, mock.Anything, []string{"x:host:a.test"}, 1, mock.Anything, mock.Anything).
+		Return(redis.NewCmdResult([]interface{}{int64(1), int64(1), int64(1000)}, nil)).Once()
+	start := time.Now()
+	res, err := client.Get("http://a.test/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, 1, calls)
+	require.True(t, time.Since(start) >= time.Millisecond*20)
+
+	clientMock.On("EvalSha", mock.Anything, mock.Anything, []string{"x:host:b.test"}, 1, mock.Anything, mock.Anything).
+		Return(redis.NewCmdResult([]interface{}{int64(0), int64(1), int64(1000)}, nil))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://b.test/", nil)
+	require.NoError(t, err)
+	_, err = client.Do(req)
+	var lerr *LimitError
+	require.True(t, errors.As(err, &lerr))
+	require.True(t, errors.Is(err, counter.ErrWaitDeadline))
+	require.Equal(t, time.Second, lerr.TTL())
+	require.Equal(t, "httplimit: rate limit exceeded: counter: wait exceeds context deadline", lerr.Error())
+	require.Equal(t, 1, calls)
+
+	clientMock.On("EvalSha", mock.Anything, mock.Anything, []string{"x:host:c.test"}, 1, mock.Anything, mock.Anything).
+		Return(redis.NewCmdResult([]interface{}{int64(0), int64(0), int64(0)}, nil))
+	_, err = client.Get("http://c.test/")
+	require.True(t, errors.Is(err, counter.ErrExceedsLimit))
+	require.Equal(t, 1, calls)
+
+	clientMock.AssertExpectations(t)
+}
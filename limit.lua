@@ -45,26 +45,101 @@ local function slidingWindow(key, value, size, limit)
 	return { 1, counter, currWindowRemainingDuration }
 end
 
+local function tokenBucket(key, value, size, limit)
+	value = tonumber(value)
+	local t = redis.call("time")
+	local now = t[1] * 1000 + math.floor(t[2]/1000)
+	local tokens = limit
+	local bucket = redis.call("hmget", key, "tokens", "time")
+	if bucket[1] ~= false then
+		tokens = math.min(limit, bucket[1] + (now - bucket[2]) * limit / size)
+	end
+	if tokens < value then
+		return { 0, limit - math.floor(tokens), math.ceil((value - tokens) * size / limit) }
+	end
+	tokens = tokens - value
+	local ttl = math.ceil((limit - tokens) * size / limit)
+	if ttl > 0 then
+		redis.call("hset", key, "tokens", tokens, "time", now)
+		redis.call("pexpire", key, ttl)
+	end
+	return { 1, limit - math.floor(tokens), ttl }
+end
+
+local function gcra(key, value, size, limit)
+	local t = redis.call("time")
+	local now = t[1] * 1000 + math.floor(t[2]/1000)
+	local interval = size / limit
+	local tat = redis.call("get", key)
+	if tat == false then
+		tat = now
+	else
+		tat = math.max(tonumber(tat), now)
+	end
+	local newTat = tat + value * interval
+	local allowAt = newTat - size
+	if allowAt > now then
+		return { 0, math.ceil((tat - now) / interval), math.ceil(allowAt - now) }
+	end
+	local ttl = math.ceil(newTat - now)
+	if ttl > 0 then
+		redis.call("set", key, newTat, "px", ttl)
+	end
+	return { 1, math.ceil((newTat - now) / interval), ttl }
+end
+
+local function slidingLog(key, value, size, limit)
+	value = tonumber(value)
+	local t = redis.call("time")
+	local now = t[1] * 1000 + math.floor(t[2]/1000)
+	local sumKey = key .. ":sum"
+	local counter = tonumber(redis.call("hget", sumKey, "sum")) or 0
+	local expired = redis.call("zrangebyscore", key, "-inf", now - size)
+	if #expired > 0 then
+		for _, hit in ipairs(expired) do
+			counter = counter - tonumber(string.match(hit, ":(%d+)$"))
+		end
+		redis.call("zremrangebyscore", key, "-inf", now - size)
+		redis.call("hset", sumKey, "sum", counter)
+	end
+	local ok = 0
+	if counter + value <= limit then
+		ok = 1
+		if value > 0 then
+			local seq = redis.call("hincrby", sumKey, "seq", 1)
+			redis.call("zadd", key, now, seq .. ":" .. value)
+			counter = redis.call("hincrby", sumKey, "sum", value)
+			redis.call("pexpire", key, size)
+			redis.call("pexpire", sumKey, size)
+		end
+	end
+	local ttl = 0
+	local oldest = redis.call("zrange", key, 0, 0, "withscores")
+	if oldest[2] ~= nil then
+		ttl = oldest[2] + size - now
+	end
+	return { ok, counter, ttl }
+end
+
 local z = 0
-local limit, v, result
+local limit, v
+local result = {}
 for i, key in ipairs(KEYS) do
 	z = z + 4
 	limit = tonumber(ARGV[z - 1])
 	if ARGV[z] == "1" then
 		v = fixedWindow(key, ARGV[z - 3], ARGV[z - 2], limit)
-	else
+	elseif ARGV[z] == "2" then
 		v = slidingWindow(key, ARGV[z - 3], ARGV[z - 2], limit)
+	elseif ARGV[z] == "3" then
+		v = tokenBucket(key, ARGV[z - 3], ARGV[z - 2], limit)
+	elseif ARGV[z] == "4" then
+		v = gcra(key, ARGV[z - 3], ARGV[z - 2], limit)
+	else
+		v = slidingLog(key, ARGV[z - 3], ARGV[z - 2], limit)
 	end
-	if i == 1 then -- first result
-		result = { v[1], v[2], v[3], limit }
-	elseif v[1] == 1 then -- ok
-		if result[1] == 1 and result[4] - result[2] > limit - v[2] then -- minimal remainder
-			result = { v[1], v[2], v[3], limit }
-		end
-	elseif result[1] == 1 then -- not ok first time
-		result = { v[1], v[2], v[3], limit }
-	elseif result[3] < v[3] then -- maximum TTL
-		result = { v[1], v[2], v[3], limit }
-	end
+	result[i * 3 - 2] = v[1]
+	result[i * 3 - 1] = v[2]
+	result[i * 3] = v[3]
 end
 return result
\ No newline at end of file
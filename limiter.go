@@ -3,11 +3,10 @@ package counter
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"math/rand"
 	"strconv"
 	"time"
-
-	"github.com/go-redis/redis/v8"
 )
 
 var random *rand.Rand
@@ -20,32 +19,68 @@ func init() {
 type Limiter interface {
 	// Limit applies the limit.
 	Limit(ctx context.Context, key string) (Result, error)
+	// LimitN applies the limit n times at once, charges n times the rate of each limit,
+	// for instance to limit by request weight.
+	// LimitN with zero n charges nothing and returns the result of Peek, negative n fails with ErrNegativeN.
+	LimitN(ctx context.Context, key string, n int) (Result, error)
+	// Peek returns the result of the limit without applying it.
+	// The result is OK if the application of the limit would succeed.
+	Peek(ctx context.Context, key string) (Result, error)
+	// Refund gives back n applications of the limit taken by Limit or LimitN which returned the taken result,
+	// to the windows of the taken result, see Counter.Refund. Refund with negative n fails with ErrNegativeN.
+	Refund(ctx context.Context, key string, taken Result, n int) (Result, error)
+	// Reset deletes every Redis key of the limit, see Counter.Reset.
+	Reset(ctx context.Context, key string) error
 }
 
+// ErrNegativeN is the error returned by LimitN and Refund when n is negative.
+var ErrNegativeN = errors.New("counter: negative n")
+
 type params struct {
-	prefix string
-	alg    int
-	rate   int
-	size   int
-	limit  int64
+	name     string
+	prefix   string
+	alg      int
+	rate     int
+	size     int
+	limit    int64
+	calendar *calendar
 }
 
 const (
-	algFixed   = 1
-	algSliding = 2
+	algFixed       = 1
+	algSliding     = 2
+	algTokenBucket = 3
+	algGCRA        = 4
+	algSlidingLog  = 5
 )
 
 // WithLimit creates parameters to build a limit.
 //
 // By default a limit uses fixed window algorithm, may be set with options.
-// Each limit is created with pseudo-random name which may be set with options.
+// With token bucket algorithm the bucket holds at most limit tokens and is refilled with limit tokens every size.
+// The limit may be named with options, every Redis key of the unnamed limit is prefixed with pseudo-random prefix.
 // The rate of decreasing the window size on each next application of the limit by default equal 1, may be set with options.
+//
+// The size is rounded up to milliseconds. WithLimit panics if the size is not positive,
+// or the limit is zero with token bucket or generic cell rate algorithm.
 func WithLimit(size time.Duration, limit uint, options ...func(*params)) *params {
-	p := &params{alg: algFixed, size: int(size / time.Millisecond), limit: int64(limit)}
+	if size <= 0 {
+		panic(errors.New("counter: limit requires positive size"))
+	}
+	p := newParams(millis(size), limit, options)
+	if p.limit == 0 && (p.alg == algTokenBucket || p.alg == algGCRA) {
+		panic(errors.New("counter: token bucket and generic cell rate algorithm require positive limit"))
+	}
+	return p
+}
+
+func newParams(size int, limit uint, options []func(*params)) *params {
+	p := &params{alg: algFixed, size: size, limit: int64(limit)}
 	for _, opt := range options {
 		opt(p)
 	}
-	if p.prefix == "" {
+	p.prefix = p.name + ":"
+	if p.name == "" {
 		p.prefix = strconv.Itoa(random.Int()) + ":"
 	}
 	if p.rate == 0 {
@@ -54,6 +89,19 @@ func WithLimit(size time.Duration, limit uint, options ...func(*params)) *params
 	return p
 }
 
+// WithQuota creates parameters to build a limit using fixed window algorithm
+// with the windows aligned to the calendar periods in the location.
+//
+// The limit may be named with options, every Redis key of the unnamed limit is prefixed with pseudo-random prefix.
+// The rate of decreasing the window size on each next application of the limit by default equal 1, may be set with options.
+// The algorithm options are ignored.
+func WithQuota(period Period, loc *time.Location, limit uint, options ...func(*params)) *params {
+	p := newParams(0, limit, options)
+	p.alg = algFixed
+	p.calendar = &calendar{period: period, loc: loc}
+	return p
+}
+
 // WithFixedWindow sets fixed window algorithm for the limit.
 func WithFixedWindow() func(*params) {
 	return func(p *params) {
@@ -68,10 +116,31 @@ func WithSlidingWindow() func(*params) {
 	}
 }
 
+// WithSlidingLog sets sliding log algorithm for the limit.
+func WithSlidingLog() func(*params) {
+	return func(p *params) {
+		p.alg = algSlidingLog
+	}
+}
+
+// WithTokenBucket sets token bucket algorithm for the limit.
+func WithTokenBucket() func(*params) {
+	return func(p *params) {
+		p.alg = algTokenBucket
+	}
+}
+
+// WithGCRA sets generic cell rate algorithm for the limit.
+func WithGCRA() func(*params) {
+	return func(p *params) {
+		p.alg = algGCRA
+	}
+}
+
 // WithName sets unique name for the limit, every Redis key will be prefixed with this name.
 func WithName(name string) func(*params) {
 	return func(p *params) {
-		p.prefix = name + ":"
+		p.name = name
 	}
 }
 
@@ -83,93 +152,232 @@ func WithRate(rate uint) func(*params) {
 }
 
 // NewLimiter creates new limiter which implements distributed rate limiting.
-func NewLimiter(client RedisClient, first *params, rest ...*params) Limiter {
-	n := len(rest)
-	if n == 0 {
-		var scr *redis.Script
-		if first.alg == algFixed {
-			scr = fwscr
-		} else {
-			scr = swscr
-		}
-		c := &Counter{client: client, script: scr, size: first.size, limit: first.limit}
-		return &limiter{counter: c, prefix: first.prefix, rate: first.rate}
+//
+// The limiter applies every limit created with WithLimit or WithQuota, may be configured with other options.
+func NewLimiter(client RedisClient, first *params, rest ...Option) Limiter {
+	return NewStoreLimiter(NewRedisStore(client), first, rest...)
+}
+
+// NewStoreLimiter creates new limiter which implements rate limiting using the store, see NewLimiter.
+func NewStoreLimiter(store Store, first *params, rest ...Option) Limiter {
+	cfg := newConfig(append([]Option{first}, rest...))
+	cfg.failure = cfg.failure.local(cfg.limits)
+	store = cfg.store(store)
+	size := len(cfg.limits)
+	if size == 1 {
+		return &limiter{counter: NewCounter(store, first), name: first.name, prefix: first.prefix, rate: first.rate, failure: cfg.failure, observer: cfg.observer}
 	}
 
-	size := n + 1
+	names := make([]string, size)
 	prefixes := make([]string, size)
-	prefixes[0] = first.prefix
+	var calendars []*calendar
 	args := make([]interface{}, size*4)
-	args[0] = first.rate
-	args[1] = first.size
-	args[2] = first.limit
-	args[3] = first.alg
-
-	z := 0
-	for i := 0; i < n; i++ {
-		z += 4
-		prefixes[i+1] = rest[i].prefix
-		args[z] = rest[i].rate
-		args[z+1] = rest[i].size
-		args[z+2] = rest[i].limit
-		args[z+3] = rest[i].alg
+	for i, p := range cfg.limits {
+		z := i * 4
+		names[i] = p.name
+		prefixes[i] = p.prefix
+		if p.calendar != nil {
+			if calendars == nil {
+				calendars = make([]*calendar, size)
+			}
+			calendars[i] = p.calendar
+		}
+		args[z] = p.rate
+		args[z+1] = p.size
+		args[z+2] = p.limit
+		args[z+3] = p.alg
 	}
 
-	return &batchlimiter{client: client, prefixes: prefixes, args: args}
+	return &batchlimiter{store: store, names: names, prefixes: prefixes, args: args, calendars: calendars, failure: cfg.failure, observer: cfg.observer}
 }
 
 type limiter struct {
-	counter *Counter
-	prefix  string
-	rate    int
+	counter  *Counter
+	name     string
+	prefix   string
+	rate     int
+	failure  failure
+	observer Observer
+}
+
+func (lt *limiter) Limit(ctx context.Context, key string) (r Result, err error) {
+	defer lt.observer.observe(ctx, "Limit", key, time.Now(), &r, &err)
+	r, err = lt.counter.Count(ctx, lt.prefix+key, lt.rate)
+	return lt.result(key, r, err, func(f Limiter) (Result, error) {
+		return f.Limit(ctx, key)
+	})
+}
+
+func (lt *limiter) LimitN(ctx context.Context, key string, n int) (r Result, err error) {
+	defer lt.observer.observe(ctx, "LimitN", key, time.Now(), &r, &err)
+	if n < 0 {
+		return Result{}, ErrNegativeN
+	}
+	if n == 0 {
+		return lt.peek(ctx, key)
+	}
+	r, err = lt.counter.Count(ctx, lt.prefix+key, n*lt.rate)
+	return lt.result(key, r, err, func(f Limiter) (Result, error) {
+		return f.LimitN(ctx, key, n)
+	})
+}
+
+func (lt *limiter) Peek(ctx context.Context, key string) (r Result, err error) {
+	defer lt.observer.observe(ctx, "Peek", key, time.Now(), &r, &err)
+	return lt.peek(ctx, key)
+}
+
+func (lt *limiter) peek(ctx context.Context, key string) (Result, error) {
+	r, err := lt.counter.peek(ctx, lt.prefix+key, lt.rate)
+	return lt.result(key, r, err, func(f Limiter) (Result, error) {
+		return f.Peek(ctx, key)
+	})
+}
+
+func (lt *limiter) Refund(ctx context.Context, key string, taken Result, n int) (r Result, err error) {
+	defer lt.observer.observe(ctx, "Refund", key, time.Now(), &r, &err)
+	if n < 0 {
+		return Result{}, ErrNegativeN
+	}
+	r, err = lt.counter.Refund(ctx, lt.prefix+key, taken, n*lt.rate)
+	return lt.result(key, r, err, func(f Limiter) (Result, error) {
+		return f.Refund(ctx, key, taken, n)
+	})
+}
+
+func (lt *limiter) Reset(ctx context.Context, key string) (err error) {
+	defer lt.observer.observe(ctx, "Reset", key, time.Now(), nil, &err)
+	return lt.counter.Reset(ctx, lt.prefix+key)
 }
 
-func (lt *limiter) Limit(ctx context.Context, key string) (Result, error) {
-	return lt.counter.Count(ctx, lt.prefix+key, lt.rate)
+// result applies the failure policy to the error, sets the result of the limit.
+func (lt *limiter) result(key string, r Result, err error, fallback func(lt Limiter) (Result, error)) (Result, error) {
+	if err != nil {
+		r, err = lt.failure.handle(err, lt.counter.degraded(lt.prefix+key), fallback)
+		if err != nil {
+			return r, err
+		}
+	}
+	if r.limits == nil {
+		r.limits = []LimitResult{{Result: r, name: lt.name, alg: lt.counter.Algorithm()}}
+	}
+	return r, nil
 }
 
 type batchlimiter struct {
-	client   RedisClient
-	prefixes []string
-	args     []interface{}
+	store     Store
+	names     []string
+	prefixes  []string
+	args      []interface{}
+	calendars []*calendar
+	failure   failure
+	observer  Observer
 }
 
 //go:embed limit.lua
 var ltsrc string
-var ltscr = redis.NewScript(ltsrc)
+var ltscr = newScript("limit", ltsrc)
 
-func (blt *batchlimiter) Limit(ctx context.Context, key string) (Result, error) {
-	keys := make([]string, len(blt.prefixes))
-	for i := 0; i < len(blt.prefixes); i++ {
-		keys[i] = blt.prefixes[i] + key
+func (blt *batchlimiter) Limit(ctx context.Context, key string) (r Result, err error) {
+	defer blt.observer.observe(ctx, "Limit", key, time.Now(), &r, &err)
+	return blt.run(ctx, ltscr, key, 1, nil, func(f Limiter) (Result, error) {
+		return f.Limit(ctx, key)
+	})
+}
+
+func (blt *batchlimiter) LimitN(ctx context.Context, key string, n int) (r Result, err error) {
+	defer blt.observer.observe(ctx, "LimitN", key, time.Now(), &r, &err)
+	if n < 0 {
+		return Result{}, ErrNegativeN
 	}
-	r := Result{}
-	res, err := ltscr.Run(ctx, blt.client, keys, blt.args...).Result()
-	if err != nil {
-		return r, err
+	if n == 0 {
+		return blt.peek(ctx, key)
 	}
-	arr, ok := res.([]interface{})
-	if !ok {
-		return r, ErrUnexpectedRedisResponse
+	return blt.run(ctx, ltscr, key, n, nil, func(f Limiter) (Result, error) {
+		return f.LimitN(ctx, key, n)
+	})
+}
+
+func (blt *batchlimiter) Peek(ctx context.Context, key string) (r Result, err error) {
+	defer blt.observer.observe(ctx, "Peek", key, time.Now(), &r, &err)
+	return blt.peek(ctx, key)
+}
+
+func (blt *batchlimiter) peek(ctx context.Context, key string) (Result, error) {
+	return blt.run(ctx, pkscr, key, 1, nil, func(f Limiter) (Result, error) {
+		return f.Peek(ctx, key)
+	})
+}
+
+func (blt *batchlimiter) Refund(ctx context.Context, key string, taken Result, n int) (r Result, err error) {
+	defer blt.observer.observe(ctx, "Refund", key, time.Now(), &r, &err)
+	if n < 0 {
+		return Result{}, ErrNegativeN
 	}
-	if len(arr) != 4 {
-		return r, ErrUnexpectedRedisResponse
+	return blt.run(ctx, rfscr, key, n, blt.windows(taken), func(f Limiter) (Result, error) {
+		return f.Refund(ctx, key, taken, n)
+	})
+}
+
+// windows returns the remaining durations of the windows of the taken result of each limit,
+// the result without the results of the limits has no windows.
+func (blt *batchlimiter) windows(taken Result) []interface{} {
+	windows := make([]interface{}, len(blt.names))
+	for i := range windows {
+		var r Result
+		if len(taken.limits) == len(blt.names) {
+			r = taken.limits[i].Result
+		}
+		windows[i] = r.window()
 	}
-	r.ok, ok = arr[0].(int64)
-	if !ok {
-		return r, ErrUnexpectedRedisResponse
+	return windows
+}
+
+func (blt *batchlimiter) Reset(ctx context.Context, key string) (err error) {
+	defer blt.observer.observe(ctx, "Reset", key, time.Now(), nil, &err)
+	keys, args := blt.keys(key, 1)
+	_, err = blt.store.Run(ctx, rsscr, keys, args...)
+	return err
+}
+
+// run runs the script with the arguments of the limits followed by the extra arguments.
+func (blt *batchlimiter) run(ctx context.Context, script *Script, key string, n int, extra []interface{}, fallback func(lt Limiter) (Result, error)) (Result, error) {
+	keys, args := blt.keys(key, n)
+	res, err := blt.store.Run(ctx, script, keys, append(args[:len(args):len(args)], extra...)...)
+	if err != nil {
+		return blt.failure.handle(err, blt.degraded(args), fallback)
 	}
-	r.counter, ok = arr[1].(int64)
-	if !ok {
-		return r, ErrUnexpectedRedisResponse
+	return newBatchResult(res, blt.names, args)
+}
+
+// degraded returns the results of each limit to be returned by the fail closed policy.
+func (blt *batchlimiter) degraded(args []interface{}) []LimitResult {
+	limits := make([]LimitResult, len(blt.names))
+	for i, name := range blt.names {
+		limits[i] = LimitResult{Result: Result{ttl: int64(args[i*4+1].(int)), limit: args[i*4+2].(int64)}, name: name, alg: algScript(args[i*4+3].(int)).Name()}
 	}
-	r.ttl, ok = arr[2].(int64)
-	if !ok {
-		return r, ErrUnexpectedRedisResponse
+	return limits
+}
+
+// keys returns the keys of the limits and the script arguments with the rates of the limits multiplied by n.
+func (blt *batchlimiter) keys(key string, n int) ([]string, []interface{}) {
+	keys := make([]string, len(blt.prefixes))
+	for i := 0; i < len(blt.prefixes); i++ {
+		keys[i] = blt.prefixes[i] + key
 	}
-	r.limit, ok = arr[3].(int64)
-	if !ok {
-		return r, ErrUnexpectedRedisResponse
+	args := blt.args
+	if blt.calendars != nil || n != 1 {
+		args = make([]interface{}, len(blt.args))
+		copy(args, blt.args)
+		for i := 0; i < len(args); i += 4 {
+			args[i] = args[i].(int) * n
+		}
+		now := time.Now()
+		for i, c := range blt.calendars {
+			if c != nil {
+				keys[i], args[i*4+1] = c.window(keys[i], now)
+			}
+		}
 	}
-	return r, nil
+	return keys, args
 }
@@ -19,19 +19,34 @@ func TestNewLimiter(t *testing.T) {
 	limitv := int64(limit)
 
 	v1 := NewLimiter(clientMock, WithLimit(size, limit, WithName("x")))
-	require.Equal(t, &limiter{counter: &Counter{client: clientMock, script: fwscr, size: sizev, limit: limitv}, prefix: "x:", rate: 1}, v1)
+	require.Equal(t, &limiter{counter: &Counter{store: NewRedisStore(clientMock), script: fwscr, alg: algFixed, size: sizev, limit: limitv}, name: "x", prefix: "x:", rate: 1}, v1)
 
 	v2 := NewLimiter(clientMock, WithLimit(size, limit, WithName("x"), WithFixedWindow()))
-	require.Equal(t, &limiter{counter: &Counter{client: clientMock, script: fwscr, size: sizev, limit: limitv}, prefix: "x:", rate: 1}, v2)
+	require.Equal(t, &limiter{counter: &Counter{store: NewRedisStore(clientMock), script: fwscr, alg: algFixed, size: sizev, limit: limitv}, name: "x", prefix: "x:", rate: 1}, v2)
 
 	v3 := NewLimiter(clientMock, WithLimit(size, limit, WithName("x"), WithSlidingWindow()))
-	require.Equal(t, &limiter{counter: &Counter{client: clientMock, script: swscr, size: sizev, limit: limitv}, prefix: "x:", rate: 1}, v3)
+	require.Equal(t, &limiter{counter: &Counter{store: NewRedisStore(clientMock), script: swscr, alg: algSliding, size: sizev, limit: limitv}, name: "x", prefix: "x:", rate: 1}, v3)
 
-	v4 := NewLimiter(clientMock, WithLimit(size, limit, WithName("x"), WithRate(2)))
-	require.Equal(t, &limiter{counter: &Counter{client: clientMock, script: fwscr, size: sizev, limit: limitv}, prefix: "x:", rate: 2}, v4)
+	v4 := NewLimiter(clientMock, WithLimit(size, limit, WithName("x"), WithTokenBucket()))
+	require.Equal(t, &limiter{counter: &Counter{store: NewRedisStore(clientMock), script: tbscr, alg: algTokenBucket, size: sizev, limit: limitv}, name: "x", prefix: "x:", rate: 1}, v4)
 
-	v5 := NewLimiter(clientMock, WithLimit(size, limit, WithName("x")), WithLimit(size, limit, WithName("y")))
-	require.Equal(t, &batchlimiter{client: clientMock, prefixes: []string{"x:", "y:"}, args: []interface{}{1, sizev, limitv, algFixed, 1, sizev, limitv, algFixed}}, v5)
+	v5 := NewLimiter(clientMock, WithLimit(size, limit, WithName("x"), WithGCRA()))
+	require.Equal(t, &limiter{counter: &Counter{store: NewRedisStore(clientMock), script: gcscr, alg: algGCRA, size: sizev, limit: limitv}, name: "x", prefix: "x:", rate: 1}, v5)
+
+	v6 := NewLimiter(clientMock, WithLimit(size, limit, WithName("x"), WithSlidingLog()))
+	require.Equal(t, &limiter{counter: &Counter{store: NewRedisStore(clientMock), script: slscr, alg: algSlidingLog, size: sizev, limit: limitv}, name: "x", prefix: "x:", rate: 1}, v6)
+
+	v7 := NewLimiter(clientMock, WithQuota(Month, time.UTC, limit, WithName("x"), WithSlidingWindow()))
+	require.Equal(t, &limiter{counter: &Counter{store: NewRedisStore(clientMock), script: fwscr, alg: algFixed, limit: limitv, calendar: &calendar{period: Month, loc: time.UTC}}, name: "x", prefix: "x:", rate: 1}, v7)
+
+	v8 := NewLimiter(clientMock, WithLimit(size, limit, WithName("x")), WithQuota(Month, time.UTC, limit, WithName("y")))
+	require.Equal(t, &batchlimiter{store: NewRedisStore(clientMock), names: []string{"x", "y"}, prefixes: []string{"x:", "y:"}, args: []interface{}{1, sizev, limitv, algFixed, 1, 0, limitv, algFixed}, calendars: []*calendar{nil, {period: Month, loc: time.UTC}}}, v8)
+
+	v9 := NewLimiter(clientMock, WithLimit(size, limit, WithName("x"), WithRate(2)))
+	require.Equal(t, &limiter{counter: &Counter{store: NewRedisStore(clientMock), script: fwscr, alg: algFixed, size: sizev, limit: limitv}, name: "x", prefix: "x:", rate: 2}, v9)
+
+	v10 := NewLimiter(clientMock, WithLimit(size, limit, WithName("x")), WithLimit(size, limit, WithName("y")))
+	require.Equal(t, &batchlimiter{store: NewRedisStore(clientMock), names: []string{"x", "y"}, prefixes: []string{"x:", "y:"}, args: []interface{}{1, sizev, limitv, algFixed, 1, sizev, limitv, algFixed}}, v10)
 
 	rnd := random
 	random = rand.New(rand.NewSource(42))
@@ -39,18 +54,18 @@ func TestNewLimiter(t *testing.T) {
 		random = rnd
 	}()
 
-	v6 := NewLimiter(clientMock, WithLimit(size, limit))
-	require.Equal(t, &limiter{counter: &Counter{client: clientMock, script: fwscr, size: sizev, limit: limitv}, prefix: "3440579354231278675:", rate: 1}, v6)
+	v11 := NewLimiter(clientMock, WithLimit(size, limit))
+	require.Equal(t, &limiter{counter: &Counter{store: NewRedisStore(clientMock), script: fwscr, alg: algFixed, size: sizev, limit: limitv}, prefix: "3440579354231278675:", rate: 1}, v11)
 }
 
 func TestLimiter(t *testing.T) {
 	clientMock := &ClientMock{}
 	size := 1000
 	limit := int64(100)
-	c := &Counter{client: clientMock, script: fwscr, size: size, limit: limit}
+	c := &Counter{store: NewRedisStore(clientMock), script: fwscr, alg: algFixed, size: size, limit: limit}
 	prefix := "x:"
 	rate := 1
-	lt := &limiter{counter: c, prefix: prefix, rate: rate}
+	lt := &limiter{counter: c, name: "x", prefix: prefix, rate: rate}
 	ctx := context.Background()
 	hash := fwscr.Hash()
 
@@ -69,6 +84,15 @@ func TestLimiter(t *testing.T) {
 	require.Equal(t, int64(2), result.Counter())
 	require.Equal(t, limit-2, result.Remainder())
 	require.Equal(t, msToDuration(100), result.TTL())
+	require.Equal(t, []LimitResult{{Result: Result{ok: 1, counter: 2, ttl: 100, limit: limit, at: result.at}, name: "x", alg: "fixedwindow"}}, result.Limits())
+
+	i = []interface{}{int64(1), int64(10), int64(100)}
+	clientMock.On("EvalSha", ctx, hash, []string{"x:3"}, rate*10, size, limit).Return(redis.NewCmdResult(i, nil))
+	result, err = lt.LimitN(ctx, "3", 10)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(10), result.Counter())
+	require.Equal(t, limit-10, result.Remainder())
 
 	clientMock.AssertExpectations(t)
 }
@@ -78,61 +102,173 @@ func TestBatchLimiter(t *testing.T) {
 	rate := 1
 	size := 1000
 	limit := int64(100)
+	ylimit := int64(10)
+	names := []string{"x", "y"}
 	prefixes := []string{"x:", "y:"}
-	args := []interface{}{rate, size, limit, algFixed, rate, size, limit, algFixed}
-	blt := &batchlimiter{client: clientMock, prefixes: prefixes, args: args}
+	args := []interface{}{rate, size, limit, algFixed, rate, size, ylimit, algSliding}
+	blt := &batchlimiter{store: NewRedisStore(clientMock), names: names, prefixes: prefixes, args: args}
 	ctx := context.Background()
 	hash := ltscr.Hash()
 
 	var i interface{}
 
 	e := errors.New("redis error")
-	clientMock.On("EvalSha", ctx, hash, []string{"x:1", "y:1"}, rate, size, limit, algFixed, rate, size, limit, algFixed).Return(redis.NewCmdResult(i, e))
+	clientMock.On("EvalSha", ctx, hash, []string{"x:1", "y:1"}, rate, size, limit, algFixed, rate, size, ylimit, algSliding).Return(redis.NewCmdResult(i, e))
 	_, err := blt.Limit(ctx, "1")
 	require.Equal(t, e, err)
 
-	clientMock.On("EvalSha", ctx, hash, []string{"x:2", "y:2"}, rate, size, limit, algFixed, rate, size, limit, algFixed).Return(redis.NewCmdResult(i, nil))
+	clientMock.On("EvalSha", ctx, hash, []string{"x:2", "y:2"}, rate, size, limit, algFixed, rate, size, ylimit, algSliding).Return(redis.NewCmdResult(i, nil))
 	_, err = blt.Limit(ctx, "2")
 	require.Equal(t, ErrUnexpectedRedisResponse, err)
 
-	i = []interface{}{1, 2}
-	clientMock.On("EvalSha", ctx, hash, []string{"x:3", "y:3"}, rate, size, limit, algFixed, rate, size, limit, algFixed).Return(redis.NewCmdResult(i, nil))
+	i = []interface{}{int64(1), int64(2), int64(100)}
+	clientMock.On("EvalSha", ctx, hash, []string{"x:3", "y:3"}, rate, size, limit, algFixed, rate, size, ylimit, algSliding).Return(redis.NewCmdResult(i, nil))
 	_, err = blt.Limit(ctx, "3")
 	require.Equal(t, ErrUnexpectedRedisResponse, err)
 
-	i = []interface{}{1, 2, 100}
-	clientMock.On("EvalSha", ctx, hash, []string{"x:4", "y:4"}, rate, size, limit, algFixed, rate, size, limit, algFixed).Return(redis.NewCmdResult(i, nil))
+	i = []interface{}{1, 2, 100, 1, 2, 100}
+	clientMock.On("EvalSha", ctx, hash, []string{"x:4", "y:4"}, rate, size, limit, algFixed, rate, size, ylimit, algSliding).Return(redis.NewCmdResult(i, nil))
 	_, err = blt.Limit(ctx, "4")
 	require.Equal(t, ErrUnexpectedRedisResponse, err)
 
-	i = []interface{}{1, 2, 100, 42}
-	clientMock.On("EvalSha", ctx, hash, []string{"x:5", "y:5"}, rate, size, limit, algFixed, rate, size, limit, algFixed).Return(redis.NewCmdResult(i, nil))
+	i = []interface{}{int64(1), 2, 100, 1, 2, 100}
+	clientMock.On("EvalSha", ctx, hash, []string{"x:5", "y:5"}, rate, size, limit, algFixed, rate, size, ylimit, algSliding).Return(redis.NewCmdResult(i, nil))
 	_, err = blt.Limit(ctx, "5")
 	require.Equal(t, ErrUnexpectedRedisResponse, err)
 
-	i = []interface{}{int64(1), 2, 100, 42}
-	clientMock.On("EvalSha", ctx, hash, []string{"x:6", "y:6"}, rate, size, limit, algFixed, rate, size, limit, algFixed).Return(redis.NewCmdResult(i, nil))
+	i = []interface{}{int64(1), int64(2), 100, 1, 2, 100}
+	clientMock.On("EvalSha", ctx, hash, []string{"x:6", "y:6"}, rate, size, limit, algFixed, rate, size, ylimit, algSliding).Return(redis.NewCmdResult(i, nil))
 	_, err = blt.Limit(ctx, "6")
 	require.Equal(t, ErrUnexpectedRedisResponse, err)
 
-	i = []interface{}{int64(1), int64(2), 100, 42}
-	clientMock.On("EvalSha", ctx, hash, []string{"x:7", "y:7"}, rate, size, limit, algFixed, rate, size, limit, algFixed).Return(redis.NewCmdResult(i, nil))
+	i = []interface{}{int64(1), int64(2), int64(100), 1, 2, 100}
+	clientMock.On("EvalSha", ctx, hash, []string{"x:7", "y:7"}, rate, size, limit, algFixed, rate, size, ylimit, algSliding).Return(redis.NewCmdResult(i, nil))
 	_, err = blt.Limit(ctx, "7")
 	require.Equal(t, ErrUnexpectedRedisResponse, err)
 
-	i = []interface{}{int64(1), int64(2), int64(100), 42}
-	clientMock.On("EvalSha", ctx, hash, []string{"x:8", "y:8"}, rate, size, limit, algFixed, rate, size, limit, algFixed).Return(redis.NewCmdResult(i, nil))
-	_, err = blt.Limit(ctx, "8")
-	require.Equal(t, ErrUnexpectedRedisResponse, err)
+	i = []interface{}{int64(1), int64(2), int64(100), int64(1), int64(5), int64(200)}
+	clientMock.On("EvalSha", ctx, hash, []string{"x:8", "y:8"}, rate, size, limit, algFixed, rate, size, ylimit, algSliding).Return(redis.NewCmdResult(i, nil))
+	result, err := blt.Limit(ctx, "8")
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(5), result.Counter())
+	require.Equal(t, ylimit-5, result.Remainder())
+	require.Equal(t, msToDuration(200), result.TTL())
+	require.Equal(t, []LimitResult{
+		{Result: Result{ok: 1, counter: 2, ttl: 100, limit: limit, at: result.Limits()[0].at}, name: "x", alg: "fixedwindow"},
+		{Result: Result{ok: 1, counter: 5, ttl: 200, limit: ylimit, at: result.Limits()[1].at}, name: "y", alg: "slidingwindow"},
+	}, result.Limits())
+	require.Equal(t, "x", result.Limits()[0].Name())
+	require.Equal(t, "slidingwindow", result.Limits()[1].Algorithm())
+	require.Equal(t, limit-2, result.Limits()[0].Remainder())
+
+	i = []interface{}{int64(1), int64(2), int64(100), int64(0), int64(10), int64(200)}
+	clientMock.On("EvalSha", ctx, hash, []string{"x:9", "y:9"}, rate, size, limit, algFixed, rate, size, ylimit, algSliding).Return(redis.NewCmdResult(i, nil))
+	result, err = blt.Limit(ctx, "9")
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.Equal(t, int64(10), result.Counter())
+	require.Equal(t, int64(0), result.Remainder())
+	require.Equal(t, msToDuration(200), result.TTL())
+	require.Len(t, result.Limits(), 2)
+
+	i = []interface{}{int64(0), int64(100), int64(300), int64(0), int64(10), int64(200)}
+	clientMock.On("EvalSha", ctx, hash, []string{"x:10", "y:10"}, rate, size, limit, algFixed, rate, size, ylimit, algSliding).Return(redis.NewCmdResult(i, nil))
+	result, err = blt.Limit(ctx, "10")
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.Equal(t, int64(100), result.Counter())
+	require.Equal(t, int64(0), result.Remainder())
+	require.Equal(t, msToDuration(300), result.TTL())
+	require.Len(t, result.Limits(), 2)
 
-	i = []interface{}{int64(1), int64(2), int64(100), limit}
-	clientMock.On("EvalSha", ctx, hash, []string{"x:9", "y:9"}, rate, size, limit, algFixed, rate, size, limit, algFixed).Return(redis.NewCmdResult(i, nil))
-	result, err := blt.Limit(ctx, "9")
+	i = []interface{}{int64(1), int64(10), int64(100), int64(1), int64(10), int64(100)}
+	clientMock.On("EvalSha", ctx, hash, []string{"x:11", "y:11"}, rate*10, size, limit, algFixed, rate*10, size, ylimit, algSliding).Return(redis.NewCmdResult(i, nil))
+	result, err = blt.LimitN(ctx, "11", 10)
 	require.NoError(t, err)
 	require.True(t, result.OK())
-	require.Equal(t, int64(2), result.Counter())
-	require.Equal(t, limit-2, result.Remainder())
-	require.Equal(t, msToDuration(100), result.TTL())
+	require.Equal(t, int64(10), result.Counter())
+	require.Equal(t, int64(0), result.Remainder())
 
 	clientMock.AssertExpectations(t)
 }
+
+func TestLimiterLimits(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	limiter := NewLimiter(
+		client,
+		WithLimit(time.Second, 2, WithName("burst")),
+		WithLimit(time.Minute, 10, WithName("minute"), WithSlidingWindow()),
+	)
+	key := "key"
+	err := limiter.Reset(ctx, key)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = limiter.Limit(ctx, key)
+		require.NoError(t, err)
+	}
+
+	result, err := limiter.Limit(ctx, key)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+
+	limits := result.Limits()
+	require.Len(t, limits, 2)
+	require.Equal(t, "burst", limits[0].Name())
+	require.False(t, limits[0].OK())
+	require.Equal(t, int64(2), limits[0].Counter())
+	require.Equal(t, int64(0), limits[0].Remainder())
+	require.True(t, limits[0].TTL() > 0 && limits[0].TTL() <= time.Second)
+	require.Equal(t, "minute", limits[1].Name())
+	require.True(t, limits[1].OK())
+	require.Equal(t, int64(3), limits[1].Counter())
+	require.Equal(t, int64(7), limits[1].Remainder())
+}
+
+func TestLimitN(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	tests := map[string]Limiter{
+		"One": NewLimiter(client, WithLimit(time.Minute, 5)),
+		"Two": NewLimiter(client, WithLimit(time.Minute, 5), WithLimit(time.Minute, 10, WithSlidingWindow())),
+	}
+
+	ctx := context.Background()
+	key := "key"
+	for name, limiter := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := limiter.Reset(ctx, key)
+			require.NoError(t, err)
+
+			result, err := limiter.LimitN(ctx, key, 5)
+			require.NoError(t, err)
+			require.True(t, result.OK())
+			require.Equal(t, int64(5), result.Counter())
+
+			_, err = limiter.LimitN(ctx, key, -3)
+			require.Equal(t, ErrNegativeN, err)
+
+			result, err = limiter.LimitN(ctx, key, 0)
+			require.NoError(t, err)
+			require.False(t, result.OK())
+			require.Equal(t, int64(5), result.Counter())
+
+			err = limiter.Reset(ctx, key)
+			require.NoError(t, err)
+
+			result, err = limiter.LimitN(ctx, key, 0)
+			require.NoError(t, err)
+			require.True(t, result.OK())
+			require.Equal(t, int64(0), result.Counter())
+
+			result, err = limiter.Peek(ctx, key)
+			require.NoError(t, err)
+			require.Equal(t, int64(0), result.Counter())
+		})
+	}
+}
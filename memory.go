@@ -0,0 +1,758 @@
+package counter
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrUnsupportedScript is the error returned by Memory when the script is not one of the scripts of the package,
+// or the arguments of the script are invalid.
+var ErrUnsupportedScript = errors.New("counter: unsupported script")
+
+// Memory implements Store and RedisClient storing the counters in the process memory,
+// runs the Go implementations of the scripts of the package instead of the Lua scripts.
+//
+// Memory is safe for concurrent use. The expired keys are never returned,
+// and are deleted from the memory incrementally: every call checks a few random keys
+// the same way as Redis expires the keys.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// NewMemory creates new in-memory storage.
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]*entry)}
+}
+
+type entry struct {
+	value   interface{} // float64, *bucket, *zset or *hitlog
+	expires int64       // unix time in milliseconds, 0 if the key never expires
+}
+
+type bucket struct {
+	tokens float64
+	time   float64
+}
+
+type member struct {
+	score float64
+	name  string
+}
+
+type zset []member
+
+// hitlog is the log of the sliding log algorithm, every hit is stored once with its weight.
+type hitlog struct {
+	hits []hit
+	sum  float64
+}
+
+type hit struct {
+	at     float64
+	weight float64
+}
+
+type memoryScript func(m *Memory, now int64, keys []string, args []interface{}) (interface{}, error)
+
+var memoryScripts = map[string]memoryScript{
+	fwscr.name: single((*Memory).fixedWindow),
+	swscr.name: single((*Memory).slidingWindow),
+	tbscr.name: single((*Memory).tokenBucket),
+	gcscr.name: single((*Memory).gcra),
+	slscr.name: single((*Memory).slidingLog),
+	ltscr.name: batch([]algorithm{
+		algFixed:       (*Memory).fixedWindow,
+		algSliding:     (*Memory).slidingWindow,
+		algTokenBucket: (*Memory).tokenBucket,
+		algGCRA:        (*Memory).gcra,
+		algSlidingLog:  (*Memory).slidingLog,
+	}),
+	pkscr.name: batch([]algorithm{
+		algFixed:       (*Memory).peekFixedWindow,
+		algSliding:     (*Memory).peekSlidingWindow,
+		algTokenBucket: (*Memory).peekTokenBucket,
+		algGCRA:        (*Memory).peekGCRA,
+		algSlidingLog:  (*Memory).peekSlidingLog,
+	}),
+	rfscr.name: (*Memory).refund,
+	rsscr.name: (*Memory).reset,
+	acscr.name: (*Memory).acquire,
+	rlscr.name: (*Memory).release,
+	rvscr.name: (*Memory).reserve,
+	cnscr.name: (*Memory).cancel,
+}
+
+var memoryHashes = func() map[string]string {
+	hashes := make(map[string]string)
+	for _, s := range []*Script{fwscr, swscr, tbscr, gcscr, slscr, ltscr, pkscr, rfscr, rsscr, acscr, rlscr, rvscr, cnscr} {
+		hashes[s.Hash()] = s.name
+	}
+	return hashes
+}()
+
+// Run runs the script of the package.
+func (m *Memory) Run(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error) {
+	return m.run(ctx, script.name, keys, args)
+}
+
+// Eval runs the script of the package.
+func (m *Memory) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	h := sha1.Sum([]byte(script))
+	return m.EvalSha(ctx, hex.EncodeToString(h[:]), keys, args...)
+}
+
+// EvalSha runs the script of the package by the SHA1 digest of the script.
+func (m *Memory) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	return redis.NewCmdResult(m.run(ctx, memoryHashes[sha1], keys, args))
+}
+
+func (m *Memory) run(ctx context.Context, name string, keys []string, args []interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	fn, ok := memoryScripts[name]
+	if !ok {
+		return nil, ErrUnsupportedScript
+	}
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+	return fn(m, now, keys, args)
+}
+
+// ScriptExists checks if the scripts are the scripts of the package.
+func (m *Memory) ScriptExists(ctx context.Context, hashes ...string) *redis.BoolSliceCmd {
+	exists := make([]bool, len(hashes))
+	for i, h := range hashes {
+		_, exists[i] = memoryHashes[h]
+	}
+	return redis.NewBoolSliceResult(exists, nil)
+}
+
+// ScriptLoad returns the SHA1 digest of the script of the package.
+func (m *Memory) ScriptLoad(ctx context.Context, script string) *redis.StringCmd {
+	h := sha1.Sum([]byte(script))
+	s := hex.EncodeToString(h[:])
+	if _, ok := memoryHashes[s]; !ok {
+		return redis.NewStringResult("", ErrUnsupportedScript)
+	}
+	return redis.NewStringResult(s, nil)
+}
+
+const (
+	sweepSize   = 20 // the number of the keys checked at once
+	sweepRounds = 4  // the maximum number of the checks per call
+)
+
+// sweep checks the random keys and deletes the expired ones,
+// checks again while more than a quarter of the checked keys are expired.
+func (m *Memory) sweep(now int64) {
+	for i := 0; i < sweepRounds; i++ {
+		checked, expired := 0, 0
+		for k, e := range m.entries { // the map iteration starts at a random entry
+			if checked == sweepSize {
+				break
+			}
+			checked++
+			if e.expires != 0 && now > e.expires {
+				delete(m.entries, k)
+				expired++
+			}
+		}
+		if expired*4 <= checked {
+			return
+		}
+	}
+}
+
+func (m *Memory) get(key string, now int64) *entry {
+	e, ok := m.entries[key]
+	if !ok {
+		return nil
+	}
+	if e.expires != 0 && now > e.expires {
+		delete(m.entries, key)
+		return nil
+	}
+	return e
+}
+
+func (m *Memory) number(key string, now int64) (float64, bool) {
+	e := m.get(key, now)
+	if e == nil {
+		return 0, false
+	}
+	v, _ := e.value.(float64)
+	return v, true
+}
+
+func (m *Memory) set(key string, value interface{}, ttl int64, now int64) {
+	m.entries[key] = &entry{value: value, expires: now + ttl}
+}
+
+func (m *Memory) pttl(key string, now int64) int64 {
+	e := m.get(key, now)
+	if e == nil {
+		return -2
+	}
+	if e.expires == 0 {
+		return -1
+	}
+	return e.expires - now
+}
+
+func (m *Memory) pexpire(key string, ttl int64, now int64) {
+	if e := m.get(key, now); e != nil {
+		e.expires = now + ttl
+	}
+}
+
+func (m *Memory) zset(key string, now int64) *zset {
+	if e := m.get(key, now); e != nil {
+		if z, ok := e.value.(*zset); ok {
+			return z
+		}
+	}
+	return &zset{}
+}
+
+// store stores the sorted set, deletes the key if the sorted set is empty.
+func (m *Memory) store(key string, z *zset, now int64) {
+	if len(*z) == 0 {
+		delete(m.entries, key)
+		return
+	}
+	if e := m.get(key, now); e != nil {
+		e.value = z
+		return
+	}
+	m.entries[key] = &entry{value: z}
+}
+
+func (z *zset) add(score float64, name string) {
+	z.remove(name)
+	i := sort.Search(len(*z), func(i int) bool {
+		v := (*z)[i]
+		return v.score > score || (v.score == score && v.name > name)
+	})
+	*z = append(*z, member{})
+	copy((*z)[i+1:], (*z)[i:])
+	(*z)[i] = member{score: score, name: name}
+}
+
+func (z *zset) remove(name string) int64 {
+	for i, v := range *z {
+		if v.name == name {
+			*z = append((*z)[:i], (*z)[i+1:]...)
+			return 1
+		}
+	}
+	return 0
+}
+
+// removeTo removes the members with the score less than or equal to max.
+func (z *zset) removeTo(max float64) {
+	i := sort.Search(len(*z), func(i int) bool { return (*z)[i].score > max })
+	*z = (*z)[i:]
+}
+
+func (l *hitlog) add(at, weight float64) {
+	i := sort.Search(len(l.hits), func(i int) bool { return l.hits[i].at > at })
+	l.hits = append(l.hits, hit{})
+	copy(l.hits[i+1:], l.hits[i:])
+	l.hits[i] = hit{at: at, weight: weight}
+	l.sum += weight
+}
+
+// removeTo removes the hits at the time less than or equal to max.
+func (l *hitlog) removeTo(max float64) {
+	i := sort.Search(len(l.hits), func(i int) bool { return l.hits[i].at > max })
+	for _, h := range l.hits[:i] {
+		l.sum -= h.weight
+	}
+	l.hits = l.hits[i:]
+}
+
+// after returns the log of the hits at the time greater than min.
+func (l *hitlog) after(min float64) *hitlog {
+	v := &hitlog{hits: l.hits, sum: l.sum}
+	v.removeTo(min)
+	return v
+}
+
+// ttl returns the time until the oldest hit expires.
+func (l *hitlog) ttl(now int64, size float64) float64 {
+	if len(l.hits) == 0 {
+		return 0
+	}
+	return l.hits[0].at + size - float64(now)
+}
+
+type algorithm func(m *Memory, now int64, key string, value, size, limit float64) []interface{}
+
+func single(fn algorithm) memoryScript {
+	return func(m *Memory, now int64, keys []string, args []interface{}) (interface{}, error) {
+		if len(keys) != 1 || len(args) != 3 {
+			return nil, ErrUnsupportedScript
+		}
+		size := toFloat(args[1])
+		if size < 1 {
+			return nil, ErrUnsupportedScript
+		}
+		return fn(m, now, keys[0], toFloat(args[0]), size, toFloat(args[2])), nil
+	}
+}
+
+func batch(fns []algorithm) memoryScript {
+	return func(m *Memory, now int64, keys []string, args []interface{}) (interface{}, error) {
+		if !validArgs(keys, args) {
+			return nil, ErrUnsupportedScript
+		}
+		result := make([]interface{}, 0, len(keys)*3)
+		for i, key := range keys {
+			z := i * 4
+			alg := int(toFloat(args[z+3]))
+			if alg < algFixed || alg > algSlidingLog {
+				alg = algSlidingLog
+			}
+			v := fns[alg](m, now, key, toFloat(args[z]), toFloat(args[z+1]), toFloat(args[z+2]))
+			result = append(result, v...)
+		}
+		return result, nil
+	}
+}
+
+// refund runs the refund script, the arguments of the limits are followed by
+// the remaining duration of the window in which each limit has taken the value.
+func (m *Memory) refund(now int64, keys []string, args []interface{}) (interface{}, error) {
+	n := len(keys) * 4
+	if len(args) != n+len(keys) || !validArgs(keys, args[:n]) {
+		return nil, ErrUnsupportedScript
+	}
+	result := make([]interface{}, 0, len(keys)*3)
+	for i, key := range keys {
+		z := i * 4
+		value, size, limit, window := toFloat(args[z]), toFloat(args[z+1]), toFloat(args[z+2]), toFloat(args[n+i])
+		var v []interface{}
+		switch int(toFloat(args[z+3])) {
+		case algFixed:
+			v = m.refundFixedWindow(now, key, value, size, limit, window)
+		case algSliding:
+			v = m.refundSlidingWindow(now, key, value, size, limit, window)
+		case algTokenBucket:
+			v = m.refundTokenBucket(now, key, value, size, limit)
+		case algGCRA:
+			v = m.refundGCRA(now, key, value, size, limit)
+		default:
+			v = m.refundSlidingLog(now, key, value, size, limit)
+		}
+		result = append(result, v...)
+	}
+	return result, nil
+}
+
+// validArgs checks if the arguments are 4 per key, and the size of every limit is positive.
+func validArgs(keys []string, args []interface{}) bool {
+	if len(args) != len(keys)*4 {
+		return false
+	}
+	for i := 1; i < len(args); i += 4 {
+		if toFloat(args[i]) < 1 {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Memory) fixedWindow(now int64, key string, value, size, limit float64) []interface{} {
+	counter, ok := m.number(key, now)
+	if counter+value > limit {
+		ttl := m.pttl(key, now)
+		if ttl == -2 {
+			ttl = 0
+		}
+		return triple(0, counter, float64(ttl))
+	}
+	if !ok {
+		m.set(key, value, int64(size), now)
+		return triple(1, value, size)
+	}
+	m.get(key, now).value = counter + value
+	return triple(1, counter+value, float64(m.pttl(key, now)))
+}
+
+func (m *Memory) peekFixedWindow(now int64, key string, value, size, limit float64) []interface{} {
+	counter, _ := m.number(key, now)
+	ttl := m.pttl(key, now)
+	if ttl < 0 {
+		ttl = 0
+	}
+	if counter+value > limit {
+		return triple(0, counter, float64(ttl))
+	}
+	return triple(1, counter, float64(ttl))
+}
+
+func (m *Memory) refundFixedWindow(now int64, key string, value, size, limit, window float64) []interface{} {
+	counter, ok := m.number(key, now)
+	if !ok {
+		return triple(1, 0, 0)
+	}
+	ttl := float64(m.pttl(key, now))
+	if ttl > window { // the window has ended, the key is of the next window
+		return triple(1, counter, ttl)
+	}
+	value = math.Min(value, counter)
+	if value > 0 {
+		counter -= value
+		m.get(key, now).value = counter
+	}
+	return triple(1, counter, ttl)
+}
+
+// windows returns the keys of the current and previous windows and the remaining duration of the current window.
+func windows(now int64, key string, size float64) (string, string, float64) {
+	currWindowTime := now - now%int64(size)
+	currWindowKey := key + ":" + strconv.FormatInt(currWindowTime, 10)
+	prevWindowKey := key + ":" + strconv.FormatInt(currWindowTime-int64(size), 10)
+	return currWindowKey, prevWindowKey, size - float64(now-currWindowTime)
+}
+
+func (m *Memory) slidingWindow(now int64, key string, value, size, limit float64) []interface{} {
+	currWindowKey, prevWindowKey, currWindowRemainingDuration := windows(now, key, size)
+	currWindowCounter, ok := m.number(currWindowKey, now)
+	prevWindowCounter, _ := m.number(prevWindowKey, now)
+	slidingWindowCounter := math.Floor(prevWindowCounter*(currWindowRemainingDuration/size) + currWindowCounter)
+	counter := slidingWindowCounter + value
+	if counter > limit {
+		return triple(0, slidingWindowCounter, currWindowRemainingDuration)
+	}
+	if !ok {
+		m.set(currWindowKey, value, int64(size*2), now)
+	} else {
+		m.get(currWindowKey, now).value = currWindowCounter + value
+	}
+	return triple(1, counter, currWindowRemainingDuration)
+}
+
+func (m *Memory) peekSlidingWindow(now int64, key string, value, size, limit float64) []interface{} {
+	currWindowKey, prevWindowKey, currWindowRemainingDuration := windows(now, key, size)
+	currWindowCounter, _ := m.number(currWindowKey, now)
+	prevWindowCounter, _ := m.number(prevWindowKey, now)
+	slidingWindowCounter := math.Floor(prevWindowCounter*(currWindowRemainingDuration/size) + currWindowCounter)
+	if slidingWindowCounter+value > limit {
+		return triple(0, slidingWindowCounter, currWindowRemainingDuration)
+	}
+	return triple(1, slidingWindowCounter, currWindowRemainingDuration)
+}
+
+func (m *Memory) refundSlidingWindow(now int64, key string, value, size, limit, window float64) []interface{} {
+	currWindowKey, prevWindowKey, currWindowRemainingDuration := windows(now, key, size)
+	currWindowCounter, _ := m.number(currWindowKey, now)
+	prevWindowCounter, _ := m.number(prevWindowKey, now)
+	// the window in which the value was taken ends at the multiple of the size following the remaining duration
+	windowEnd := math.Floor((float64(now)+window)/size) * size
+	switch windowEnd - float64(now) {
+	case currWindowRemainingDuration:
+		value = math.Min(value, currWindowCounter)
+		if value > 0 {
+			currWindowCounter -= value
+			m.get(currWindowKey, now).value = currWindowCounter
+		}
+	case currWindowRemainingDuration - size:
+		value = math.Min(value, prevWindowCounter)
+		if value > 0 {
+			prevWindowCounter -= value
+			m.get(prevWindowKey, now).value = prevWindowCounter
+		}
+	}
+	slidingWindowCounter := math.Floor(prevWindowCounter*(currWindowRemainingDuration/size) + currWindowCounter)
+	return triple(1, slidingWindowCounter, currWindowRemainingDuration)
+}
+
+func (m *Memory) bucket(key string, now int64) *bucket {
+	if e := m.get(key, now); e != nil {
+		if b, ok := e.value.(*bucket); ok {
+			return b
+		}
+	}
+	return nil
+}
+
+func (m *Memory) tokenBucket(now int64, key string, value, size, limit float64) []interface{} {
+	tokens := limit
+	if b := m.bucket(key, now); b != nil {
+		tokens = math.Min(limit, b.tokens+(float64(now)-b.time)*limit/size)
+	}
+	if tokens < value {
+		return triple(0, limit-math.Floor(tokens), math.Ceil((value-tokens)*size/limit))
+	}
+	tokens = tokens - value
+	ttl := math.Ceil((limit - tokens) * size / limit)
+	if ttl > 0 {
+		m.set(key, &bucket{tokens: tokens, time: float64(now)}, int64(ttl), now)
+	}
+	return triple(1, limit-math.Floor(tokens), ttl)
+}
+
+func (m *Memory) peekTokenBucket(now int64, key string, value, size, limit float64) []interface{} {
+	tokens := limit
+	if b := m.bucket(key, now); b != nil {
+		tokens = math.Min(limit, b.tokens+(float64(now)-b.time)*limit/size)
+	}
+	if tokens < value {
+		return triple(0, limit-math.Floor(tokens), math.Ceil((value-tokens)*size/limit))
+	}
+	return triple(1, limit-math.Floor(tokens), math.Ceil((limit-tokens)*size/limit))
+}
+
+func (m *Memory) refundTokenBucket(now int64, key string, value, size, limit float64) []interface{} {
+	b := m.bucket(key, now)
+	if b == nil {
+		return triple(1, 0, 0)
+	}
+	tokens := math.Min(limit, b.tokens+(float64(now)-b.time)*limit/size+value)
+	ttl := math.Ceil((limit - tokens) * size / limit)
+	if ttl > 0 {
+		m.set(key, &bucket{tokens: tokens, time: float64(now)}, int64(ttl), now)
+	} else {
+		delete(m.entries, key)
+	}
+	return triple(1, limit-math.Floor(tokens), ttl)
+}
+
+func (m *Memory) gcra(now int64, key string, value, size, limit float64) []interface{} {
+	interval := size / limit
+	tat, ok := m.number(key, now)
+	if !ok {
+		tat = float64(now)
+	} else {
+		tat = math.Max(tat, float64(now))
+	}
+	newTat := tat + value*interval
+	allowAt := newTat - size
+	if allowAt > float64(now) {
+		return triple(0, math.Ceil((tat-float64(now))/interval), math.Ceil(allowAt-float64(now)))
+	}
+	ttl := math.Ceil(newTat - float64(now))
+	if ttl > 0 {
+		m.set(key, newTat, int64(ttl), now)
+	}
+	return triple(1, math.Ceil((newTat-float64(now))/interval), ttl)
+}
+
+func (m *Memory) peekGCRA(now int64, key string, value, size, limit float64) []interface{} {
+	interval := size / limit
+	tat, ok := m.number(key, now)
+	if !ok {
+		tat = float64(now)
+	} else {
+		tat = math.Max(tat, float64(now))
+	}
+	allowAt := tat + value*interval - size
+	if allowAt > float64(now) {
+		return triple(0, math.Ceil((tat-float64(now))/interval), math.Ceil(allowAt-float64(now)))
+	}
+	return triple(1, math.Ceil((tat-float64(now))/interval), math.Ceil(tat-float64(now)))
+}
+
+func (m *Memory) refundGCRA(now int64, key string, value, size, limit float64) []interface{} {
+	interval := size / limit
+	tat, ok := m.number(key, now)
+	if !ok {
+		return triple(1, 0, 0)
+	}
+	tat = math.Max(tat-value*interval, float64(now))
+	ttl := math.Ceil(tat - float64(now))
+	if ttl > 0 {
+		m.set(key, tat, int64(ttl), now)
+	} else {
+		delete(m.entries, key)
+	}
+	return triple(1, math.Ceil((tat-float64(now))/interval), ttl)
+}
+
+func (m *Memory) log(key string, now int64) *hitlog {
+	if e := m.get(key, now); e != nil {
+		if l, ok := e.value.(*hitlog); ok {
+			return l
+		}
+	}
+	return &hitlog{}
+}
+
+func (m *Memory) slidingLog(now int64, key string, value, size, limit float64) []interface{} {
+	l := m.log(key, now)
+	l.removeTo(float64(now) - size)
+	ok := 0.0
+	if l.sum+value <= limit {
+		ok = 1
+		if value > 0 {
+			l.add(float64(now), value)
+		}
+	}
+	if len(l.hits) == 0 {
+		delete(m.entries, key)
+	} else if ok == 1 && value > 0 {
+		m.set(key, l, int64(size), now)
+	}
+	return triple(ok, l.sum, l.ttl(now, size))
+}
+
+func (m *Memory) peekSlidingLog(now int64, key string, value, size, limit float64) []interface{} {
+	l := m.log(key, now).after(float64(now) - size)
+	if l.sum+value > limit {
+		return triple(0, l.sum, l.ttl(now, size))
+	}
+	return triple(1, l.sum, l.ttl(now, size))
+}
+
+func (m *Memory) refundSlidingLog(now int64, key string, value, size, limit float64) []interface{} {
+	l := m.log(key, now)
+	l.removeTo(float64(now) - size)
+	for value > 0 && len(l.hits) != 0 {
+		last := &l.hits[len(l.hits)-1]
+		w := math.Min(last.weight, value)
+		last.weight -= w
+		l.sum -= w
+		value -= w
+		if last.weight == 0 {
+			l.hits = l.hits[:len(l.hits)-1]
+		}
+	}
+	if len(l.hits) == 0 {
+		delete(m.entries, key)
+	}
+	return triple(1, l.sum, l.ttl(now, size))
+}
+
+func (m *Memory) reset(now int64, keys []string, args []interface{}) (interface{}, error) {
+	if !validArgs(keys, args) {
+		return nil, ErrUnsupportedScript
+	}
+	for i, key := range keys {
+		z := i * 4
+		if int(toFloat(args[z+3])) == algSliding {
+			size := int64(toFloat(args[z+1]))
+			currWindowTime := now - now%size
+			for j := int64(0); j < 3; j++ {
+				delete(m.entries, key+":"+strconv.FormatInt(currWindowTime-size*j, 10))
+			}
+		} else {
+			delete(m.entries, key)
+		}
+	}
+	return int64(len(keys)), nil
+}
+
+func (m *Memory) acquire(now int64, keys []string, args []interface{}) (interface{}, error) {
+	if len(keys) != 1 || len(args) != 3 {
+		return nil, ErrUnsupportedScript
+	}
+	key, id, ttl, limit := keys[0], fmt.Sprint(args[0]), toFloat(args[1]), toFloat(args[2])
+	z := m.zset(key, now)
+	z.removeTo(float64(now))
+	m.store(key, z, now)
+	counter := float64(len(*z))
+	if counter+1 > limit {
+		if len(*z) == 0 {
+			return triple(0, counter, 0), nil
+		}
+		return triple(0, counter, (*z)[0].score-float64(now)), nil
+	}
+	z.add(float64(now)+ttl, id)
+	m.store(key, z, now)
+	m.pexpire(key, int64(ttl), now)
+	return triple(1, counter+1, ttl), nil
+}
+
+func (m *Memory) release(now int64, keys []string, args []interface{}) (interface{}, error) {
+	if len(keys) != 1 || len(args) != 1 {
+		return nil, ErrUnsupportedScript
+	}
+	z := m.zset(keys[0], now)
+	n := z.remove(fmt.Sprint(args[0]))
+	m.store(keys[0], z, now)
+	return n, nil
+}
+
+func (m *Memory) reserve(now int64, keys []string, args []interface{}) (interface{}, error) {
+	if len(keys) != 1 || len(args) != 3 {
+		return nil, ErrUnsupportedScript
+	}
+	key, value, size, limit := keys[0], toFloat(args[0]), toFloat(args[1]), toFloat(args[2])
+	interval := size / limit
+	tat, ok := m.number(key, now)
+	if !ok {
+		tat = float64(now)
+	} else {
+		tat = math.Max(tat, float64(now))
+	}
+	if value > limit {
+		return append(triple(0, math.Min(limit, math.Ceil((tat-float64(now))/interval)), math.Ceil(tat-float64(now))), int64(0), int64(0)), nil
+	}
+	newTat := tat + value*interval
+	delay := math.Max(0, math.Ceil(newTat-size-float64(now)))
+	ttl := math.Ceil(newTat - float64(now))
+	if ttl > 0 {
+		m.set(key, newTat, int64(ttl), now)
+	}
+	return append(triple(1, math.Min(limit, math.Ceil((newTat-float64(now))/interval)), ttl), int64(delay), now+int64(delay)), nil
+}
+
+func (m *Memory) cancel(now int64, keys []string, args []interface{}) (interface{}, error) {
+	if len(keys) != 1 || len(args) != 4 {
+		return nil, ErrUnsupportedScript
+	}
+	key, value, size, limit, at := keys[0], toFloat(args[0]), toFloat(args[1]), toFloat(args[2]), toFloat(args[3])
+	if float64(now) >= at {
+		return int64(0), nil
+	}
+	tat, ok := m.number(key, now)
+	if !ok {
+		return int64(0), nil
+	}
+	tat = math.Max(tat-value*size/limit, float64(now))
+	ttl := math.Ceil(tat - float64(now))
+	if ttl > 0 {
+		m.set(key, tat, int64(ttl), now)
+	} else {
+		delete(m.entries, key)
+	}
+	return int64(1), nil
+}
+
+// triple converts the numbers to integers the same way as Redis converts Lua numbers.
+func triple(ok, counter, ttl float64) []interface{} {
+	return []interface{}{int64(ok), int64(counter), int64(ttl)}
+}
+
+func toFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	f, _ := strconv.ParseFloat(fmt.Sprint(v), 64)
+	return f
+}
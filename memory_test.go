@@ -0,0 +1,263 @@
+package counter
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryFixedWindow(t *testing.T) {
+	testFixedWindow(t, NewMemory())
+}
+
+func TestMemorySlidingWindow(t *testing.T) {
+	testSlidingWindow(t, NewMemory())
+}
+
+func TestMemorySlidingLog(t *testing.T) {
+	testSlidingLog(t, NewMemory())
+	testSlidingLogWeight(t, NewMemory())
+}
+
+func TestMemoryRefundWindowEnded(t *testing.T) {
+	testRefundWindowEnded(t, NewMemory())
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	memory := NewMemory()
+	ctx := context.Background()
+	limiter := NewLimiter(
+		memory,
+		WithLimit(time.Second, 3, WithName("fixed")),
+		WithLimit(time.Second*2, 5, WithName("sliding"), WithSlidingWindow()),
+		WithLimit(time.Second, 4, WithName("bucket"), WithTokenBucket()),
+		WithLimit(time.Second, 4, WithName("gcra"), WithGCRA()),
+		WithLimit(time.Second, 4, WithName("log"), WithSlidingLog()),
+	)
+	key := "key"
+
+	var taken Result
+	for i := 1; i <= 3; i++ {
+		result, err := limiter.Limit(ctx, key)
+		require.NoError(t, err)
+		require.True(t, result.OK())
+		require.Len(t, result.Limits(), 5)
+		for _, v := range result.Limits() {
+			require.Equal(t, int64(i), v.Counter(), v.Name())
+		}
+		taken = result
+	}
+
+	result, err := limiter.Limit(ctx, key)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.Equal(t, "fixed", result.Limits()[0].Name())
+	require.False(t, result.Limits()[0].OK())
+	require.Equal(t, int64(3), result.Counter())
+
+	result, err = limiter.Peek(ctx, key)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.Equal(t, int64(3), result.Limits()[0].Counter())
+
+	result, err = limiter.Refund(ctx, key, taken, 2)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(1), result.Limits()[0].Counter())
+	require.Equal(t, int64(2), result.Limits()[4].Counter())
+
+	err = limiter.Reset(ctx, key)
+	require.NoError(t, err)
+
+	result, err = limiter.Peek(ctx, key)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	for _, v := range result.Limits() {
+		require.Equal(t, int64(0), v.Counter(), v.Name())
+	}
+	require.Empty(t, memory.entries)
+}
+
+func TestMemoryCounters(t *testing.T) {
+	memory := NewMemory()
+	ctx := context.Background()
+
+	result, err := TokenBucket(memory, time.Millisecond*100, 2).Count(ctx, "bucket", 3)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.Equal(t, msToDuration(100), result.TTL())
+
+	result, err = GCRA(memory, time.Second, 2).Count(ctx, "gcra", 2)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(2), result.Counter())
+
+	result, err = SlidingLog(memory, time.Second, 2).Count(ctx, "log", 2)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(2), result.Counter())
+	require.Equal(t, time.Second, result.TTL())
+
+	r, err := GCRA(memory, time.Second, 2).Reserve(ctx, "gcra", 1)
+	require.NoError(t, err)
+	require.True(t, r.OK())
+	require.InDelta(t, time.Millisecond*500, r.Delay(), float64(time.Millisecond*10))
+	err = r.Cancel(ctx)
+	require.NoError(t, err)
+
+	cl := NewConcurrencyLimiter(memory, time.Second, 1)
+	lease, err := cl.Acquire(ctx, "lease")
+	require.NoError(t, err)
+	require.True(t, lease.OK())
+	l, err := cl.Acquire(ctx, "lease")
+	require.NoError(t, err)
+	require.False(t, l.OK())
+	err = lease.Release(ctx)
+	require.NoError(t, err)
+	l, err = cl.Acquire(ctx, "lease")
+	require.NoError(t, err)
+	require.True(t, l.OK())
+}
+
+func TestMemoryExpiration(t *testing.T) {
+	memory := NewMemory()
+	ctx := context.Background()
+	c := FixedWindow(memory, time.Millisecond*50, 1)
+
+	result, err := c.Count(ctx, "key", 1)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+
+	result, err = c.Count(ctx, "key", 1)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+
+	time.Sleep(time.Millisecond * 60)
+
+	result, err = c.Count(ctx, "key", 1)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+
+	time.Sleep(time.Millisecond * 60)
+	_, err = c.Peek(ctx, "other")
+	require.NoError(t, err)
+	require.Empty(t, memory.entries)
+}
+
+func TestMemorySweep(t *testing.T) {
+	memory := NewMemory()
+	ctx := context.Background()
+	c := FixedWindow(memory, time.Millisecond*100, 1)
+
+	for i := 0; i < 1000; i++ {
+		_, err := c.Count(ctx, strconv.Itoa(i), 1)
+		require.NoError(t, err)
+	}
+	require.True(t, len(memory.entries) > 900)
+
+	time.Sleep(time.Millisecond * 110)
+	_, err := c.Peek(ctx, "other")
+	require.NoError(t, err)
+	n := len(memory.entries)
+	require.True(t, n > 900-sweepSize*sweepRounds) // every call checks a few keys only
+
+	for i := 0; i < 100 && len(memory.entries) != 0; i++ {
+		_, err = c.Peek(ctx, "other")
+		require.NoError(t, err)
+	}
+	require.Empty(t, memory.entries)
+}
+
+func TestMemoryInvalidSize(t *testing.T) {
+	memory := NewMemory()
+	ctx := context.Background()
+	for _, scr := range []*Script{fwscr, swscr, tbscr, gcscr, slscr} {
+		_, err := memory.Run(ctx, scr, []string{"key"}, 1, 0, 10)
+		require.Equal(t, ErrUnsupportedScript, err, scr.Name())
+	}
+	for _, scr := range []*Script{ltscr, pkscr, rfscr, rsscr} {
+		_, err := memory.Run(ctx, scr, []string{"x", "y"}, 1, 1000, 10, algFixed, 1, 0, 10, algSliding)
+		require.Equal(t, ErrUnsupportedScript, err, scr.Name())
+	}
+	require.Empty(t, memory.entries)
+}
+
+func TestMemoryConcurrency(t *testing.T) {
+	memory := NewMemory()
+	ctx := context.Background()
+	c := FixedWindow(memory, time.Second, 50)
+
+	var mu sync.Mutex
+	ok := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := c.Count(ctx, "key", 1)
+			require.NoError(t, err)
+			if result.OK() {
+				mu.Lock()
+				ok++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 50, ok)
+}
+
+func TestMemoryScript(t *testing.T) {
+	memory := NewMemory()
+	ctx := context.Background()
+
+	err := memory.Eval(ctx, "return 1", nil).Err()
+	require.Equal(t, ErrUnsupportedScript, err)
+
+	err = memory.ScriptLoad(ctx, "return 1").Err()
+	require.Equal(t, ErrUnsupportedScript, err)
+
+	hash, err := memory.ScriptLoad(ctx, fwsrc).Result()
+	require.NoError(t, err)
+	require.Equal(t, fwscr.Hash(), hash)
+
+	exists, err := memory.ScriptExists(ctx, fwscr.Hash(), "0").Result()
+	require.NoError(t, err)
+	require.Equal(t, []bool{true, false}, exists)
+
+	res, err := memory.Eval(ctx, fwsrc, []string{"key"}, 1, 1000, 10).Result()
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{int64(1), int64(1), int64(1000)}, res)
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = fwscr.script.Run(cctx, memory, []string{"key"}, 1, 1000, 10).Err()
+	require.Equal(t, context.Canceled, err)
+
+	var _ RedisClient = memory
+	var _ redis.Scripter = memory
+	var _ Store = memory
+}
+
+func TestMemoryStore(t *testing.T) {
+	memory := NewMemory()
+	ctx := context.Background()
+
+	limiter := NewStoreLimiter(memory, WithLimit(time.Second, 1, WithName("x")))
+	result, err := limiter.Limit(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, result.OK())
+
+	result, err = NewCounter(memory, WithLimit(time.Second, 1)).Count(ctx, "x:key", 1)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.Equal(t, int64(1), result.Counter())
+
+	_, err = memory.Run(ctx, &Script{name: "unknown"}, nil)
+	require.Equal(t, ErrUnsupportedScript, err)
+}
@@ -0,0 +1,46 @@
+package counter
+
+import (
+	"context"
+	"time"
+)
+
+// Observer is called after every operation of the limiter or the counter with the decision,
+// for instance to log every denial or to audit the decisions. The observer must not block.
+type Observer func(ctx context.Context, d Decision)
+
+// Decision is the outcome of the operation of the limiter or the counter.
+type Decision struct {
+	// Operation is the name of the method: Limit, LimitN, Peek, Refund or Reset of the limiter,
+	// Count, Peek, Refund or Reset of the counter.
+	Operation string
+	// Key is the key of the operation, without the prefixes of the limits.
+	Key string
+	// Result is the result of the operation, with the result of each limit of the limiter, see Result.Limits.
+	// The result is empty if the operation fails or has no result.
+	Result Result
+	// Latency is the duration of the operation, including the failure policy.
+	Latency time.Duration
+	// Err is the error of the operation.
+	Err error
+}
+
+// WithObserver sets the observer of every decision of the limiter or the counter.
+func WithObserver(o Observer) Option {
+	return option(func(c *config) {
+		c.observer = o
+	})
+}
+
+// observe calls the observer if it is set, is deferred at the start of the operation,
+// so the result and the error are passed by pointers, the result pointer is nil if the operation has no result.
+func (o Observer) observe(ctx context.Context, op, key string, start time.Time, r *Result, err *error) {
+	if o == nil {
+		return
+	}
+	d := Decision{Operation: op, Key: key, Latency: time.Since(start), Err: *err}
+	if r != nil {
+		d.Result = *r
+	}
+	o(ctx, d)
+}
@@ -0,0 +1,83 @@
+package counter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserver(t *testing.T) {
+	ctx := context.Background()
+	var decisions []Decision
+	observer := WithObserver(func(ctx context.Context, d Decision) {
+		decisions = append(decisions, d)
+	})
+	memory := NewMemory()
+	x := WithLimit(time.Minute, 1, WithName("x"))
+	y := WithLimit(time.Minute, 5, WithName("y"))
+
+	limiter := NewStoreLimiter(memory, x, y, observer)
+	for i := 0; i < 2; i++ {
+		_, err := limiter.Limit(ctx, "key")
+		require.NoError(t, err)
+	}
+	_, err := limiter.Refund(ctx, "key", Result{}, 1)
+	require.NoError(t, err)
+	require.NoError(t, limiter.Reset(ctx, "key"))
+
+	require.Len(t, decisions, 4)
+	require.Equal(t, "Limit", decisions[0].Operation)
+	require.Equal(t, "key", decisions[0].Key)
+	require.True(t, decisions[0].Result.OK())
+	require.NoError(t, decisions[0].Err)
+	require.True(t, decisions[0].Latency > 0)
+	require.False(t, decisions[1].Result.OK())
+	require.Len(t, decisions[1].Result.Limits(), 2)
+	require.Equal(t, "x", decisions[1].Result.Limits()[0].Name())
+	require.False(t, decisions[1].Result.Limits()[0].OK())
+	require.Equal(t, "Refund", decisions[2].Operation)
+	require.Equal(t, "Reset", decisions[3].Operation)
+
+	decisions = nil
+	limiter = NewStoreLimiter(memory, x, observer)
+	_, err = limiter.LimitN(ctx, "key", 1)
+	require.NoError(t, err)
+	_, err = limiter.Peek(ctx, "key")
+	require.NoError(t, err)
+	require.Len(t, decisions, 2)
+	require.Equal(t, "LimitN", decisions[0].Operation)
+	require.Equal(t, "key", decisions[0].Key)
+	require.Equal(t, "x", decisions[0].Result.Limits()[0].Name())
+	require.Equal(t, "Peek", decisions[1].Operation)
+	require.False(t, decisions[1].Result.OK())
+
+	decisions = nil
+	e := errors.New("connection refused")
+	store := storeFunc(func(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error) {
+		return nil, e
+	})
+	c := NewCounter(store, x, observer)
+	_, err = c.Count(ctx, "key", 1)
+	require.Equal(t, e, err)
+	require.Equal(t, e, c.Reset(ctx, "key"))
+
+	c = NewCounter(store, x, observer, WithFailClosed())
+	_, err = c.Peek(ctx, "key")
+	require.NoError(t, err)
+	_, err = c.Refund(ctx, "key", Result{}, 1)
+	require.NoError(t, err)
+
+	require.Len(t, decisions, 4)
+	require.Equal(t, "Count", decisions[0].Operation)
+	require.Equal(t, e, decisions[0].Err)
+	require.Equal(t, "Reset", decisions[1].Operation)
+	require.Equal(t, e, decisions[1].Err)
+	require.Equal(t, "Peek", decisions[2].Operation)
+	require.NoError(t, decisions[2].Err)
+	require.False(t, decisions[2].Result.OK())
+	require.True(t, decisions[2].Result.Degraded())
+	require.Equal(t, "Refund", decisions[3].Operation)
+}
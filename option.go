@@ -0,0 +1,46 @@
+package counter
+
+import "time"
+
+// Option is the option of the limiter or the counter.
+//
+// The limit parameters created with WithLimit and WithQuota are the options of the limiter,
+// the counter ignores the limit parameters.
+type Option interface {
+	apply(*config)
+}
+
+type config struct {
+	limits   []*params
+	failure  failure
+	breaker  breaker
+	timeout  time.Duration
+	observer Observer
+}
+
+type option func(*config)
+
+func (fn option) apply(c *config) {
+	fn(c)
+}
+
+func (p *params) apply(c *config) {
+	c.limits = append(c.limits, p)
+}
+
+func newConfig(options []Option) *config {
+	c := &config{}
+	for _, opt := range options {
+		opt.apply(c)
+	}
+	return c
+}
+
+// store wraps the store with the timeout and the circuit breaker if set,
+// the circuit breaker counts the calls which time out as failed.
+func (c *config) store(store Store) Store {
+	if c.timeout > 0 {
+		store = &timeoutStore{store: store, timeout: c.timeout}
+	}
+	return c.breaker.wrap(store)
+}
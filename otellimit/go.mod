@@ -0,0 +1,15 @@
+module github.com/da440dil/go-counter/otellimit
+
+go 1.16
+
+// The replace directive is for the development in this repository only,
+// the released module requires the tagged release of github.com/da440dil/go-counter.
+replace github.com/da440dil/go-counter => ../
+
+require (
+	github.com/da440dil/go-counter v0.6.0
+	github.com/stretchr/testify v1.7.1
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
+)
@@ -0,0 +1,198 @@
+// Package otellimit provides OpenTelemetry tracing for distributed rate limiting.
+package otellimit
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/da440dil/go-counter"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/da440dil/go-counter/otellimit"
+
+// Attribute keys of the spans.
+//
+// The counter span has the algorithm of the counter, the limiter span has the algorithms
+// of the limits in the order of the limits.
+const (
+	AlgorithmKey  = attribute.Key("ratelimit.algorithm")
+	AlgorithmsKey = attribute.Key("ratelimit.algorithms")
+	LimitsKey     = attribute.Key("ratelimit.limits")
+	AllowedKey    = attribute.Key("ratelimit.allowed")
+	RemainingKey  = attribute.Key("ratelimit.remaining")
+	DegradedKey   = attribute.Key("ratelimit.degraded")
+	DeniedKey     = attribute.Key("ratelimit.denied")
+)
+
+type params struct {
+	provider trace.TracerProvider
+}
+
+// WithTracerProvider sets the tracer provider, by default the global tracer provider is used.
+func WithTracerProvider(tp trace.TracerProvider) func(*params) {
+	return func(p *params) {
+		p.provider = tp
+	}
+}
+
+func newTracer(options []func(*params)) trace.Tracer {
+	p := &params{}
+	for _, opt := range options {
+		opt(p)
+	}
+	if p.provider == nil {
+		p.provider = otel.GetTracerProvider()
+	}
+	return p.provider.Tracer(instrumentationName)
+}
+
+// NewLimiter creates new limiter which creates a span for every operation of the limiter.
+//
+// The span of the operation which returns the result has the number of the limits, the algorithms of the limits,
+// the outcome, the remaining capacity and the names of the limits which deny the operation, the unnamed limit
+// is named by its index, "limit_0" for the first limit. The error is recorded on the span. The key is not recorded.
+func NewLimiter(lt counter.Limiter, options ...func(*params)) counter.Limiter {
+	return &limiter{limiter: lt, tracer: newTracer(options)}
+}
+
+type limiter struct {
+	limiter counter.Limiter
+	tracer  trace.Tracer
+}
+
+func (lt *limiter) Limit(ctx context.Context, key string) (counter.Result, error) {
+	ctx, span := lt.tracer.Start(ctx, "ratelimit.Limit")
+	defer span.End()
+	r, err := lt.limiter.Limit(ctx, key)
+	end(span, r, err)
+	return r, err
+}
+
+func (lt *limiter) LimitN(ctx context.Context, key string, n int) (counter.Result, error) {
+	ctx, span := lt.tracer.Start(ctx, "ratelimit.LimitN")
+	defer span.End()
+	r, err := lt.limiter.LimitN(ctx, key, n)
+	end(span, r, err)
+	return r, err
+}
+
+func (lt *limiter) Peek(ctx context.Context, key string) (counter.Result, error) {
+	ctx, span := lt.tracer.Start(ctx, "ratelimit.Peek")
+	defer span.End()
+	r, err := lt.limiter.Peek(ctx, key)
+	end(span, r, err)
+	return r, err
+}
+
+func (lt *limiter) Refund(ctx context.Context, key string, taken counter.Result, n int) (counter.Result, error) {
+	ctx, span := lt.tracer.Start(ctx, "ratelimit.Refund")
+	defer span.End()
+	r, err := lt.limiter.Refund(ctx, key, taken, n)
+	end(span, r, err)
+	return r, err
+}
+
+func (lt *limiter) Reset(ctx context.Context, key string) error {
+	ctx, span := lt.tracer.Start(ctx, "ratelimit.Reset")
+	defer span.End()
+	err := lt.limiter.Reset(ctx, key)
+	fail(span, err)
+	return err
+}
+
+// NewCounter creates new counter which creates a span for every operation of the counter,
+// the spans have the algorithm of the counter, see NewLimiter.
+func NewCounter(c *counter.Counter, options ...func(*params)) *Counter {
+	return &Counter{counter: c, tracer: newTracer(options), algorithm: AlgorithmKey.String(c.Algorithm())}
+}
+
+// Counter is the counter instrumented with the tracer.
+type Counter struct {
+	counter   *counter.Counter
+	tracer    trace.Tracer
+	algorithm attribute.KeyValue
+}
+
+// Count increments key value by specified value, see counter.Counter.Count.
+func (c *Counter) Count(ctx context.Context, key string, value int) (counter.Result, error) {
+	ctx, span := c.tracer.Start(ctx, "ratelimit.Count", trace.WithAttributes(c.algorithm))
+	defer span.End()
+	r, err := c.counter.Count(ctx, key, value)
+	end(span, r, err)
+	return r, err
+}
+
+// Peek returns current counter value without incrementing, see counter.Counter.Peek.
+func (c *Counter) Peek(ctx context.Context, key string) (counter.Result, error) {
+	ctx, span := c.tracer.Start(ctx, "ratelimit.Peek", trace.WithAttributes(c.algorithm))
+	defer span.End()
+	r, err := c.counter.Peek(ctx, key)
+	end(span, r, err)
+	return r, err
+}
+
+// Refund decrements key value by specified value, see counter.Counter.Refund.
+func (c *Counter) Refund(ctx context.Context, key string, taken counter.Result, value int) (counter.Result, error) {
+	ctx, span := c.tracer.Start(ctx, "ratelimit.Refund", trace.WithAttributes(c.algorithm))
+	defer span.End()
+	r, err := c.counter.Refund(ctx, key, taken, value)
+	end(span, r, err)
+	return r, err
+}
+
+// Reset deletes key value, see counter.Counter.Reset.
+func (c *Counter) Reset(ctx context.Context, key string) error {
+	ctx, span := c.tracer.Start(ctx, "ratelimit.Reset", trace.WithAttributes(c.algorithm))
+	defer span.End()
+	err := c.counter.Reset(ctx, key)
+	fail(span, err)
+	return err
+}
+
+// end sets the attributes of the result on the span, or records the error.
+func end(span trace.Span, r counter.Result, err error) {
+	if fail(span, err) {
+		return
+	}
+	limits := len(r.Limits())
+	if limits == 0 { // the counter result
+		limits = 1
+	}
+	var algorithms, denied []string
+	for i, l := range r.Limits() {
+		algorithms = append(algorithms, l.Algorithm())
+		if !l.OK() {
+			name := l.Name()
+			if name == "" {
+				name = "limit_" + strconv.Itoa(i)
+			}
+			denied = append(denied, name)
+		}
+	}
+	if len(algorithms) != 0 { // the counter span has the algorithm of the counter
+		span.SetAttributes(AlgorithmsKey.StringSlice(algorithms))
+	}
+	span.SetAttributes(
+		LimitsKey.Int(limits),
+		AllowedKey.Bool(r.OK()),
+		RemainingKey.Int64(r.Remainder()),
+		DegradedKey.Bool(r.Degraded()),
+	)
+	if len(denied) != 0 {
+		span.SetAttributes(DeniedKey.StringSlice(denied))
+	}
+}
+
+// fail records the error on the span and sets the error status.
+func fail(span trace.Span, err error) bool {
+	if err == nil {
+		return false
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return true
+}
@@ -0,0 +1,123 @@
+package otellimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/da440dil/go-counter"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type storeFunc func(ctx context.Context, script *counter.Script, keys []string, args ...interface{}) (interface{}, error)
+
+func (fn storeFunc) Run(ctx context.Context, script *counter.Script, keys []string, args ...interface{}) (interface{}, error) {
+	return fn(ctx, script, keys, args...)
+}
+
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestLimiter(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	ctx := context.Background()
+
+	lt := NewLimiter(counter.NewStoreLimiter(
+		counter.NewMemory(),
+		counter.WithLimit(time.Minute, 1, counter.WithName("x")),
+		counter.WithLimit(time.Minute, 5, counter.WithName("y"), counter.WithGCRA()),
+	), WithTracerProvider(tp))
+	for i := 0; i < 2; i++ {
+		_, err := lt.Limit(ctx, "key")
+		require.NoError(t, err)
+	}
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	require.Equal(t, "ratelimit.Limit", spans[0].Name)
+	attrs := attributes(spans[0])
+	require.Equal(t, int64(2), attrs[LimitsKey].AsInt64())
+	require.Equal(t, []string{"fixedwindow", "gcra"}, attrs[AlgorithmsKey].AsStringSlice())
+	require.NotContains(t, attrs, AlgorithmKey)
+	require.True(t, attrs[AllowedKey].AsBool())
+	require.Equal(t, int64(0), attrs[RemainingKey].AsInt64())
+	require.False(t, attrs[DegradedKey].AsBool())
+	require.NotContains(t, attrs, DeniedKey)
+
+	attrs = attributes(spans[1])
+	require.False(t, attrs[AllowedKey].AsBool())
+	require.Equal(t, []string{"x"}, attrs[DeniedKey].AsStringSlice())
+	require.Equal(t, codes.Unset, spans[1].Status.Code)
+}
+
+func TestUnnamedLimits(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	ctx := context.Background()
+
+	lt := NewLimiter(counter.NewStoreLimiter(
+		counter.NewMemory(),
+		counter.WithLimit(time.Minute, 5),
+		counter.WithLimit(time.Minute, 1),
+	), WithTracerProvider(tp))
+	for i := 0; i < 2; i++ {
+		_, err := lt.Limit(ctx, "key")
+		require.NoError(t, err)
+	}
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	require.Equal(t, []string{"limit_1"}, attributes(spans[1])[DeniedKey].AsStringSlice())
+}
+
+func TestCounter(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	ctx := context.Background()
+	var e error
+	store := storeFunc(func(ctx context.Context, script *counter.Script, keys []string, args ...interface{}) (interface{}, error) {
+		if e != nil {
+			return nil, e
+		}
+		return []interface{}{int64(1), int64(2), int64(1000)}, nil
+	})
+	c := NewCounter(counter.NewCounter(store, counter.WithLimit(time.Second, 10, counter.WithGCRA())), WithTracerProvider(tp))
+
+	_, err := c.Count(ctx, "key", 2)
+	require.NoError(t, err)
+
+	e = errors.New("connection refused")
+	_, err = c.Count(ctx, "key", 1)
+	require.Equal(t, e, err)
+	require.Equal(t, e, c.Reset(ctx, "key"))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+	attrs := attributes(spans[0])
+	require.Equal(t, "ratelimit.Count", spans[0].Name)
+	require.Equal(t, "gcra", attrs[AlgorithmKey].AsString())
+	require.NotContains(t, attrs, AlgorithmsKey)
+	require.Equal(t, int64(1), attrs[LimitsKey].AsInt64())
+	require.True(t, attrs[AllowedKey].AsBool())
+	require.Equal(t, int64(8), attrs[RemainingKey].AsInt64())
+
+	for _, span := range spans[1:] {
+		require.Equal(t, codes.Error, span.Status.Code)
+		require.Equal(t, e.Error(), span.Status.Description)
+		require.Len(t, span.Events, 1)
+		require.Equal(t, "exception", span.Events[0].Name)
+		require.NotContains(t, attributes(span), AllowedKey)
+	}
+	require.Equal(t, "ratelimit.Reset", spans[2].Name)
+}
@@ -0,0 +1,109 @@
+local function fixedWindow(key, value, size, limit)
+	local counter = redis.call("get", key)
+	if counter == false then
+		counter = 0
+	end
+	local ttl = redis.call("pttl", key)
+	if ttl < 0 then
+		ttl = 0
+	end
+	if counter + value > limit then
+		return { 0, tonumber(counter), ttl }
+	end
+	return { 1, tonumber(counter), ttl }
+end
+
+local function slidingWindow(key, value, size, limit)
+	local t = redis.call("time")
+	local now = t[1] * 1000 + math.floor(t[2]/1000)
+	local currWindowTime = now - now % size
+	local currWindowKey = key .. ":" .. currWindowTime
+	local prevWindowKey = key .. ":" .. currWindowTime - size
+	local currWindowCounter = redis.call("get", currWindowKey)
+	if currWindowCounter == false then
+		currWindowCounter = 0
+	end
+	local prevWindowCounter = redis.call("get", prevWindowKey)
+	if prevWindowCounter == false then
+		prevWindowCounter = 0
+	end
+	local currWindowRemainingDuration = size - (now - currWindowTime)
+	local slidingWindowCounter = math.floor(prevWindowCounter * (currWindowRemainingDuration / size) + currWindowCounter)
+	if slidingWindowCounter + value > limit then
+		return { 0, slidingWindowCounter, currWindowRemainingDuration }
+	end
+	return { 1, slidingWindowCounter, currWindowRemainingDuration }
+end
+
+local function tokenBucket(key, value, size, limit)
+	value = tonumber(value)
+	local t = redis.call("time")
+	local now = t[1] * 1000 + math.floor(t[2]/1000)
+	local tokens = limit
+	local bucket = redis.call("hmget", key, "tokens", "time")
+	if bucket[1] ~= false then
+		tokens = math.min(limit, bucket[1] + (now - bucket[2]) * limit / size)
+	end
+	if tokens < value then
+		return { 0, limit - math.floor(tokens), math.ceil((value - tokens) * size / limit) }
+	end
+	return { 1, limit - math.floor(tokens), math.ceil((limit - tokens) * size / limit) }
+end
+
+local function gcra(key, value, size, limit)
+	local t = redis.call("time")
+	local now = t[1] * 1000 + math.floor(t[2]/1000)
+	local interval = size / limit
+	local tat = redis.call("get", key)
+	if tat == false then
+		tat = now
+	else
+		tat = math.max(tonumber(tat), now)
+	end
+	local allowAt = tat + value * interval - size
+	if allowAt > now then
+		return { 0, math.ceil((tat - now) / interval), math.ceil(allowAt - now) }
+	end
+	return { 1, math.ceil((tat - now) / interval), math.ceil(tat - now) }
+end
+
+local function slidingLog(key, value, size, limit)
+	local t = redis.call("time")
+	local now = t[1] * 1000 + math.floor(t[2]/1000)
+	local counter = tonumber(redis.call("hget", key .. ":sum", "sum")) or 0
+	for _, hit in ipairs(redis.call("zrangebyscore", key, "-inf", now - size)) do
+		counter = counter - tonumber(string.match(hit, ":(%d+)$"))
+	end
+	local ttl = 0
+	local oldest = redis.call("zrangebyscore", key, "(" .. (now - size), "+inf", "withscores", "limit", 0, 1)
+	if oldest[2] ~= nil then
+		ttl = oldest[2] + size - now
+	end
+	if counter + value > limit then
+		return { 0, counter, ttl }
+	end
+	return { 1, counter, ttl }
+end
+
+local z = 0
+local limit, v
+local result = {}
+for i, key in ipairs(KEYS) do
+	z = z + 4
+	limit = tonumber(ARGV[z - 1])
+	if ARGV[z] == "1" then
+		v = fixedWindow(key, ARGV[z - 3], ARGV[z - 2], limit)
+	elseif ARGV[z] == "2" then
+		v = slidingWindow(key, ARGV[z - 3], ARGV[z - 2], limit)
+	elseif ARGV[z] == "3" then
+		v = tokenBucket(key, ARGV[z - 3], ARGV[z - 2], limit)
+	elseif ARGV[z] == "4" then
+		v = gcra(key, ARGV[z - 3], ARGV[z - 2], limit)
+	else
+		v = slidingLog(key, ARGV[z - 3], ARGV[z - 2], limit)
+	end
+	result[i * 3 - 2] = v[1]
+	result[i * 3 - 1] = v[2]
+	result[i * 3] = v[3]
+end
+return result
\ No newline at end of file
@@ -0,0 +1,128 @@
+package counter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterPeek(t *testing.T) {
+	clientMock := &ClientMock{}
+	size := 1000
+	limit := int64(100)
+	c := &Counter{store: NewRedisStore(clientMock), script: fwscr, alg: algFixed, size: size, limit: limit}
+	ctx := context.Background()
+	hash := pkscr.Hash()
+
+	var i interface{}
+
+	e := errors.New("redis error")
+	clientMock.On("EvalSha", ctx, hash, []string{"1"}, 1, size, limit, algFixed).Return(redis.NewCmdResult(i, e))
+	_, err := c.Peek(ctx, "1")
+	require.Equal(t, e, err)
+
+	i = []interface{}{int64(1), int64(2)}
+	clientMock.On("EvalSha", ctx, hash, []string{"2"}, 1, size, limit, algFixed).Return(redis.NewCmdResult(i, nil))
+	_, err = c.Peek(ctx, "2")
+	require.Equal(t, ErrUnexpectedRedisResponse, err)
+
+	i = []interface{}{int64(1), int64(2), int64(100)}
+	clientMock.On("EvalSha", ctx, hash, []string{"3"}, 1, size, limit, algFixed).Return(redis.NewCmdResult(i, nil))
+	result, err := c.Peek(ctx, "3")
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(2), result.Counter())
+	require.Equal(t, limit-2, result.Remainder())
+	require.Equal(t, msToDuration(100), result.TTL())
+
+	clientMock.AssertExpectations(t)
+}
+
+func TestPeek(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	size := time.Minute
+	limit := uint(100)
+	tests := map[string]*Counter{
+		"FixedWindow":   FixedWindow(client, size, limit),
+		"SlidingWindow": SlidingWindow(client, size, limit),
+		"TokenBucket":   TokenBucket(client, size/time.Duration(limit), limit),
+		"GCRA":          GCRA(client, size, limit),
+		"SlidingLog":    SlidingLog(client, size, limit),
+		"Quota":         Quota(client, Day, time.UTC, limit),
+	}
+
+	ctx := context.Background()
+	key := "key"
+	for name, counter := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := counter.Reset(ctx, key)
+			require.NoError(t, err)
+
+			result, err := counter.Peek(ctx, key)
+			require.NoError(t, err)
+			require.True(t, result.OK())
+			require.Equal(t, int64(0), result.Counter())
+			require.Equal(t, int64(100), result.Remainder())
+
+			_, err = counter.Count(ctx, key, 99)
+			require.NoError(t, err)
+
+			for i := 0; i < 2; i++ {
+				result, err = counter.Peek(ctx, key)
+				require.NoError(t, err)
+				require.True(t, result.OK())
+				require.Equal(t, int64(99), result.Counter())
+				require.Equal(t, int64(1), result.Remainder())
+				require.True(t, result.TTL() > msToDuration(0) && result.TTL() <= 24*time.Hour)
+			}
+
+			_, err = counter.Count(ctx, key, 1)
+			require.NoError(t, err)
+
+			result, err = counter.Peek(ctx, key)
+			require.NoError(t, err)
+			require.False(t, result.OK())
+			require.Equal(t, int64(100), result.Counter())
+			require.Equal(t, int64(0), result.Remainder())
+			require.True(t, result.TTL() > msToDuration(0) && result.TTL() <= 24*time.Hour)
+		})
+	}
+}
+
+func TestLimiterPeek(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	limiter := NewLimiter(
+		client,
+		WithLimit(time.Second, 3),
+		WithLimit(time.Second*2, 5, WithSlidingWindow()),
+	)
+	key := "key"
+	err := limiter.Reset(ctx, key)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = limiter.Limit(ctx, key)
+		require.NoError(t, err)
+	}
+
+	result, err := limiter.Peek(ctx, key)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.Equal(t, int64(3), result.Counter())
+	require.Equal(t, int64(0), result.Remainder())
+	require.True(t, result.TTL() > msToDuration(0) && result.TTL() <= time.Second)
+
+	result, err = limiter.Limit(ctx, key)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.Equal(t, int64(3), result.Counter())
+}
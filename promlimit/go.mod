@@ -0,0 +1,13 @@
+module github.com/da440dil/go-counter/promlimit
+
+go 1.16
+
+// The replace directive is for the development in this repository only,
+// the released module requires the tagged release of github.com/da440dil/go-counter.
+replace github.com/da440dil/go-counter => ../
+
+require (
+	github.com/da440dil/go-counter v0.6.0
+	github.com/prometheus/client_golang v1.12.2
+	github.com/stretchr/testify v1.7.1
+)
@@ -0,0 +1,239 @@
+// Package promlimit provides Prometheus metrics for distributed rate limiting.
+package promlimit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/da440dil/go-counter"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector collects the metrics of the limiters and the counters instrumented with it.
+//
+// The metrics are labeled by the limit name, the operation, the script and the error type only,
+// the keys are never used as labels so the cardinality of the metrics is bounded.
+// The unnamed limit of a limiter is labeled by its index, "limit_0" for the first limit,
+// and the unnamed counter is labeled "unnamed", so the label values do not change on restart.
+type Collector struct {
+	decisions *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	errors    *prometheus.CounterVec
+	remaining *prometheus.HistogramVec
+}
+
+// NewCollector creates new collector with the metrics in the namespace.
+func NewCollector(namespace string) *Collector {
+	return &Collector{
+		decisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "ratelimit_decisions_total",
+			Help:      "Number of rate limit decisions by limit name, result and whether the decision is made by the failure policy.",
+		}, []string{"limit", "result", "degraded"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "ratelimit_duration_seconds",
+			Help:      "Duration of the store round trips by script.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"script"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "ratelimit_errors_total",
+			Help:      "Number of rate limit operations failed by operation and error type.",
+		}, []string{"operation", "type"}),
+		remaining: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "ratelimit_remaining_ratio",
+			Help:      "Remaining capacity of the limit as a fraction of the limit after each decision.",
+			Buckets:   []float64{0, .1, .25, .5, .75, .9, 1},
+		}, []string{"limit"}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.decisions.Describe(ch)
+	c.duration.Describe(ch)
+	c.errors.Describe(ch)
+	c.remaining.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.decisions.Collect(ch)
+	c.duration.Collect(ch)
+	c.errors.Collect(ch)
+	c.remaining.Collect(ch)
+}
+
+// Store instruments the store, the duration of each round trip is labeled by the script name.
+// The limiters and the counters created with the store report the duration of their store calls.
+func (c *Collector) Store(store counter.Store) counter.Store {
+	return &timedStore{store: store, collector: c}
+}
+
+// Limiter instruments the limiter, the decisions are labeled by the name of each limit.
+func (c *Collector) Limiter(lt counter.Limiter) counter.Limiter {
+	return &limiter{limiter: lt, collector: c}
+}
+
+// Counter instruments the counter, the decisions are labeled by the name, "unnamed" if the name is empty.
+func (c *Collector) Counter(ct *counter.Counter, name string) *Counter {
+	if name == "" {
+		name = unnamed
+	}
+	return &Counter{counter: ct, name: name, collector: c}
+}
+
+const unnamed = "unnamed"
+
+const (
+	opLimit  = "limit"
+	opPeek   = "peek"
+	opRefund = "refund"
+	opReset  = "reset"
+)
+
+// observe records the error of the operation, and the decision if the operation applies the limit.
+func (c *Collector) observe(op string, r counter.Result, err error, name string) {
+	if err != nil {
+		c.errors.WithLabelValues(op, errorType(err)).Inc()
+		return
+	}
+	if op != opLimit {
+		return
+	}
+	degraded := r.Degraded()
+	if name != "" {
+		c.decide(name, r, degraded)
+		return
+	}
+	for i, l := range r.Limits() {
+		name = l.Name()
+		if name == "" {
+			name = "limit_" + strconv.Itoa(i)
+		}
+		c.decide(name, l.Result, degraded)
+	}
+}
+
+// decide records the decision of the limit, the remaining capacity is not recorded for the degraded decision
+// because the failure policy does not know the counter value.
+func (c *Collector) decide(name string, r counter.Result, degraded bool) {
+	result := "allowed"
+	if !r.OK() {
+		result = "denied"
+	}
+	c.decisions.WithLabelValues(name, result, strconv.FormatBool(degraded)).Inc()
+	if degraded {
+		return
+	}
+	if limit := r.Counter() + r.Remainder(); limit > 0 {
+		remaining := r.Remainder()
+		if remaining < 0 {
+			remaining = 0
+		}
+		c.remaining.WithLabelValues(name).Observe(float64(remaining) / float64(limit))
+	}
+}
+
+// errorType returns the label of the error type.
+func errorType(err error) string {
+	switch {
+	case errors.Is(err, counter.ErrUnexpectedRedisResponse):
+		return "unexpected_response"
+	case errors.Is(err, counter.ErrTimeout):
+		return "timeout"
+	case errors.Is(err, counter.ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	}
+	return "other"
+}
+
+type timedStore struct {
+	store     counter.Store
+	collector *Collector
+}
+
+func (s *timedStore) Run(ctx context.Context, script *counter.Script, keys []string, args ...interface{}) (interface{}, error) {
+	start := time.Now()
+	res, err := s.store.Run(ctx, script, keys, args...)
+	s.collector.duration.WithLabelValues(script.Name()).Observe(time.Since(start).Seconds())
+	return res, err
+}
+
+type limiter struct {
+	limiter   counter.Limiter
+	collector *Collector
+}
+
+func (lt *limiter) Limit(ctx context.Context, key string) (counter.Result, error) {
+	r, err := lt.limiter.Limit(ctx, key)
+	lt.collector.observe(opLimit, r, err, "")
+	return r, err
+}
+
+func (lt *limiter) LimitN(ctx context.Context, key string, n int) (counter.Result, error) {
+	r, err := lt.limiter.LimitN(ctx, key, n)
+	lt.collector.observe(opLimit, r, err, "")
+	return r, err
+}
+
+func (lt *limiter) Peek(ctx context.Context, key string) (counter.Result, error) {
+	r, err := lt.limiter.Peek(ctx, key)
+	lt.collector.observe(opPeek, r, err, "")
+	return r, err
+}
+
+func (lt *limiter) Refund(ctx context.Context, key string, taken counter.Result, n int) (counter.Result, error) {
+	r, err := lt.limiter.Refund(ctx, key, taken, n)
+	lt.collector.observe(opRefund, r, err, "")
+	return r, err
+}
+
+func (lt *limiter) Reset(ctx context.Context, key string) error {
+	err := lt.limiter.Reset(ctx, key)
+	lt.collector.observe(opReset, counter.Result{}, err, "")
+	return err
+}
+
+// Counter is the counter instrumented with the collector.
+type Counter struct {
+	counter   *counter.Counter
+	name      string
+	collector *Collector
+}
+
+// Count increments key value by specified value, see counter.Counter.Count.
+func (c *Counter) Count(ctx context.Context, key string, value int) (counter.Result, error) {
+	r, err := c.counter.Count(ctx, key, value)
+	c.collector.observe(opLimit, r, err, c.name)
+	return r, err
+}
+
+// Peek returns current counter value without incrementing, see counter.Counter.Peek.
+func (c *Counter) Peek(ctx context.Context, key string) (counter.Result, error) {
+	r, err := c.counter.Peek(ctx, key)
+	c.collector.observe(opPeek, r, err, c.name)
+	return r, err
+}
+
+// Refund decrements key value by specified value, see counter.Counter.Refund.
+func (c *Counter) Refund(ctx context.Context, key string, taken counter.Result, value int) (counter.Result, error) {
+	r, err := c.counter.Refund(ctx, key, taken, value)
+	c.collector.observe(opRefund, r, err, c.name)
+	return r, err
+}
+
+// Reset deletes key value, see counter.Counter.Reset.
+func (c *Counter) Reset(ctx context.Context, key string) error {
+	err := c.counter.Reset(ctx, key)
+	c.collector.observe(opReset, counter.Result{}, err, c.name)
+	return err
+}
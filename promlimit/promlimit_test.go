@@ -0,0 +1,150 @@
+package promlimit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/da440dil/go-counter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type storeFunc func(ctx context.Context, script *counter.Script, keys []string, args ...interface{}) (interface{}, error)
+
+func (fn storeFunc) Run(ctx context.Context, script *counter.Script, keys []string, args ...interface{}) (interface{}, error) {
+	return fn(ctx, script, keys, args...)
+}
+
+func TestLimiter(t *testing.T) {
+	collector := NewCollector("test")
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	ctx := context.Background()
+	lt := collector.Limiter(counter.NewStoreLimiter(
+		collector.Store(counter.NewMemory()),
+		counter.WithLimit(time.Minute, 2, counter.WithName("x")),
+		counter.WithLimit(time.Minute, 4, counter.WithName("y")),
+	))
+	for i := 0; i < 3; i++ {
+		_, err := lt.Limit(ctx, "key")
+		require.NoError(t, err)
+	}
+	_, err := lt.Peek(ctx, "key")
+	require.NoError(t, err)
+
+	require.Equal(t, float64(2), testutil.ToFloat64(collector.decisions.WithLabelValues("x", "allowed", "false")))
+	require.Equal(t, float64(1), testutil.ToFloat64(collector.decisions.WithLabelValues("x", "denied", "false")))
+	require.Equal(t, float64(3), testutil.ToFloat64(collector.decisions.WithLabelValues("y", "allowed", "false")))
+
+	err = testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP test_ratelimit_remaining_ratio Remaining capacity of the limit as a fraction of the limit after each decision.
+# TYPE test_ratelimit_remaining_ratio histogram
+test_ratelimit_remaining_ratio_bucket{limit="x",le="0"} 2
+test_ratelimit_remaining_ratio_bucket{limit="x",le="0.1"} 2
+test_ratelimit_remaining_ratio_bucket{limit="x",le="0.25"} 2
+test_ratelimit_remaining_ratio_bucket{limit="x",le="0.5"} 3
+test_ratelimit_remaining_ratio_bucket{limit="x",le="0.75"} 3
+test_ratelimit_remaining_ratio_bucket{limit="x",le="0.9"} 3
+test_ratelimit_remaining_ratio_bucket{limit="x",le="1"} 3
+test_ratelimit_remaining_ratio_bucket{limit="x",le="+Inf"} 3
+test_ratelimit_remaining_ratio_sum{limit="x"} 0.5
+test_ratelimit_remaining_ratio_count{limit="x"} 3
+test_ratelimit_remaining_ratio_bucket{limit="y",le="0"} 0
+test_ratelimit_remaining_ratio_bucket{limit="y",le="0.1"} 0
+test_ratelimit_remaining_ratio_bucket{limit="y",le="0.25"} 1
+test_ratelimit_remaining_ratio_bucket{limit="y",le="0.5"} 2
+test_ratelimit_remaining_ratio_bucket{limit="y",le="0.75"} 3
+test_ratelimit_remaining_ratio_bucket{limit="y",le="0.9"} 3
+test_ratelimit_remaining_ratio_bucket{limit="y",le="1"} 3
+test_ratelimit_remaining_ratio_bucket{limit="y",le="+Inf"} 3
+test_ratelimit_remaining_ratio_sum{limit="y"} 1.5
+test_ratelimit_remaining_ratio_count{limit="y"} 3
+`), "test_ratelimit_remaining_ratio")
+	require.NoError(t, err)
+
+	require.Equal(t, 2, testutil.CollectAndCount(collector, "test_ratelimit_duration_seconds"))
+}
+
+func TestUnnamedLimits(t *testing.T) {
+	collector := NewCollector("")
+	ctx := context.Background()
+	lt := collector.Limiter(counter.NewStoreLimiter(
+		counter.NewMemory(),
+		counter.WithLimit(time.Minute, 1),
+		counter.WithLimit(time.Minute, 5, counter.WithName("y")),
+		counter.WithLimit(time.Minute, 5),
+	))
+	for i := 0; i < 2; i++ {
+		_, err := lt.Limit(ctx, "key")
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, float64(1), testutil.ToFloat64(collector.decisions.WithLabelValues("limit_0", "allowed", "false")))
+	require.Equal(t, float64(1), testutil.ToFloat64(collector.decisions.WithLabelValues("limit_0", "denied", "false")))
+	require.Equal(t, float64(2), testutil.ToFloat64(collector.decisions.WithLabelValues("y", "allowed", "false")))
+	require.Equal(t, float64(2), testutil.ToFloat64(collector.decisions.WithLabelValues("limit_2", "allowed", "false")))
+	require.Equal(t, 4, testutil.CollectAndCount(collector.decisions))
+}
+
+func TestCounter(t *testing.T) {
+	collector := NewCollector("")
+	ctx := context.Background()
+	var e error
+	store := storeFunc(func(ctx context.Context, script *counter.Script, keys []string, args ...interface{}) (interface{}, error) {
+		if e != nil {
+			return nil, e
+		}
+		return []interface{}{int64(1), int64(1), int64(1000)}, nil
+	})
+	c := collector.Counter(counter.NewCounter(collector.Store(store), counter.WithLimit(time.Second, 10)), "c")
+
+	result, err := c.Count(ctx, "key", 1)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, float64(1), testutil.ToFloat64(collector.decisions.WithLabelValues("c", "allowed", "false")))
+
+	tests := map[string]error{
+		"unexpected_response": counter.ErrUnexpectedRedisResponse,
+		"timeout":             counter.ErrTimeout,
+		"circuit_open":        counter.ErrCircuitOpen,
+		"canceled":            context.Canceled,
+		"deadline_exceeded":   context.DeadlineExceeded,
+		"other":               errors.New("connection refused"),
+	}
+	for name, err := range tests {
+		e = err
+		_, err = c.Count(ctx, "key", 1)
+		require.Equal(t, e, err)
+		require.Equal(t, float64(1), testutil.ToFloat64(collector.errors.WithLabelValues(opLimit, name)), name)
+	}
+
+	e = nil
+	_, err = c.Refund(ctx, "key", result, 1)
+	require.NoError(t, err)
+	_, err = c.Peek(ctx, "key")
+	require.NoError(t, err)
+	require.NoError(t, c.Reset(ctx, "key"))
+	require.Equal(t, float64(1), testutil.ToFloat64(collector.decisions.WithLabelValues("c", "allowed", "false")))
+	require.Equal(t, 4, testutil.CollectAndCount(collector.duration)) // fixedwindow, refund, peek and reset scripts
+}
+
+func TestDegraded(t *testing.T) {
+	collector := NewCollector("")
+	ctx := context.Background()
+	store := storeFunc(func(ctx context.Context, script *counter.Script, keys []string, args ...interface{}) (interface{}, error) {
+		return nil, errors.New("connection refused")
+	})
+	c := collector.Counter(counter.NewCounter(store, counter.WithLimit(time.Second, 10), counter.WithFailOpen()), "")
+
+	result, err := c.Count(ctx, "key", 1)
+	require.NoError(t, err)
+	require.True(t, result.Degraded())
+	require.Equal(t, float64(1), testutil.ToFloat64(collector.decisions.WithLabelValues("unnamed", "allowed", "true")))
+	require.Equal(t, float64(0), testutil.ToFloat64(collector.decisions.WithLabelValues("unnamed", "allowed", "false")))
+	require.Equal(t, 0, testutil.CollectAndCount(collector.remaining))
+}
@@ -0,0 +1,146 @@
+local function fixedWindow(key, value, size, limit, window)
+	local counter = redis.call("get", key)
+	if counter == false then
+		return { 1, 0, 0 }
+	end
+	counter = tonumber(counter)
+	local ttl = redis.call("pttl", key)
+	if ttl > window then
+		return { 1, counter, ttl }
+	end
+	value = math.min(tonumber(value), counter)
+	if value > 0 then
+		counter = redis.call("decrby", key, value)
+	end
+	return { 1, counter, ttl }
+end
+
+local function slidingWindow(key, value, size, limit, window)
+	local t = redis.call("time")
+	local now = t[1] * 1000 + math.floor(t[2]/1000)
+	local currWindowTime = now - now % size
+	local currWindowKey = key .. ":" .. currWindowTime
+	local prevWindowKey = key .. ":" .. currWindowTime - size
+	local currWindowCounter = redis.call("get", currWindowKey)
+	if currWindowCounter == false then
+		currWindowCounter = 0
+	end
+	local prevWindowCounter = redis.call("get", prevWindowKey)
+	if prevWindowCounter == false then
+		prevWindowCounter = 0
+	end
+	local windowTime = math.floor((now + window) / size) * size - size
+	if windowTime == currWindowTime then
+		value = math.min(tonumber(value), tonumber(currWindowCounter))
+		if value > 0 then
+			currWindowCounter = redis.call("decrby", currWindowKey, value)
+		end
+	elseif windowTime == currWindowTime - size then
+		value = math.min(tonumber(value), tonumber(prevWindowCounter))
+		if value > 0 then
+			prevWindowCounter = redis.call("decrby", prevWindowKey, value)
+		end
+	end
+	local currWindowRemainingDuration = size - (now - currWindowTime)
+	local slidingWindowCounter = math.floor(prevWindowCounter * (currWindowRemainingDuration / size) + currWindowCounter)
+	return { 1, slidingWindowCounter, currWindowRemainingDuration }
+end
+
+local function tokenBucket(key, value, size, limit)
+	local t = redis.call("time")
+	local now = t[1] * 1000 + math.floor(t[2]/1000)
+	local bucket = redis.call("hmget", key, "tokens", "time")
+	if bucket[1] == false then
+		return { 1, 0, 0 }
+	end
+	local tokens = math.min(limit, bucket[1] + (now - bucket[2]) * limit / size + value)
+	local ttl = math.ceil((limit - tokens) * size / limit)
+	if ttl > 0 then
+		redis.call("hset", key, "tokens", tokens, "time", now)
+		redis.call("pexpire", key, ttl)
+	else
+		redis.call("del", key)
+	end
+	return { 1, limit - math.floor(tokens), ttl }
+end
+
+local function gcra(key, value, size, limit)
+	local t = redis.call("time")
+	local now = t[1] * 1000 + math.floor(t[2]/1000)
+	local interval = size / limit
+	local tat = redis.call("get", key)
+	if tat == false then
+		return { 1, 0, 0 }
+	end
+	tat = math.max(tonumber(tat) - value * interval, now)
+	local ttl = math.ceil(tat - now)
+	if ttl > 0 then
+		redis.call("set", key, tat, "px", ttl)
+	else
+		redis.call("del", key)
+	end
+	return { 1, math.ceil((tat - now) / interval), ttl }
+end
+
+local function slidingLog(key, value, size, limit)
+	value = tonumber(value)
+	local t = redis.call("time")
+	local now = t[1] * 1000 + math.floor(t[2]/1000)
+	local sumKey = key .. ":sum"
+	local counter = tonumber(redis.call("hget", sumKey, "sum")) or 0
+	for _, hit in ipairs(redis.call("zrangebyscore", key, "-inf", now - size)) do
+		counter = counter - tonumber(string.match(hit, ":(%d+)$"))
+	end
+	redis.call("zremrangebyscore", key, "-inf", now - size)
+	while value > 0 do
+		local newest = redis.call("zrange", key, -1, -1, "withscores")
+		if newest[1] == nil then
+			break
+		end
+		local id, weight = string.match(newest[1], "^(%d+):(%d+)$")
+		weight = tonumber(weight)
+		redis.call("zrem", key, newest[1])
+		if weight > value then
+			redis.call("zadd", key, newest[2], id .. ":" .. weight - value)
+			weight = value
+		end
+		counter = counter - weight
+		value = value - weight
+	end
+	if redis.call("exists", key) == 1 then
+		redis.call("hset", sumKey, "sum", counter)
+	else
+		counter = 0
+		redis.call("del", sumKey)
+	end
+	local ttl = 0
+	local oldest = redis.call("zrange", key, 0, 0, "withscores")
+	if oldest[2] ~= nil then
+		ttl = oldest[2] + size - now
+	end
+	return { 1, counter, ttl }
+end
+
+local z = 0
+local limit, window, v
+local result = {}
+for i, key in ipairs(KEYS) do
+	z = z + 4
+	limit = tonumber(ARGV[z - 1])
+	window = tonumber(ARGV[#KEYS * 4 + i])
+	if ARGV[z] == "1" then
+		v = fixedWindow(key, ARGV[z - 3], ARGV[z - 2], limit, window)
+	elseif ARGV[z] == "2" then
+		v = slidingWindow(key, ARGV[z - 3], ARGV[z - 2], limit, window)
+	elseif ARGV[z] == "3" then
+		v = tokenBucket(key, ARGV[z - 3], ARGV[z - 2], limit)
+	elseif ARGV[z] == "4" then
+		v = gcra(key, ARGV[z - 3], ARGV[z - 2], limit)
+	else
+		v = slidingLog(key, ARGV[z - 3], ARGV[z - 2], limit)
+	end
+	result[i * 3 - 2] = v[1]
+	result[i * 3 - 1] = v[2]
+	result[i * 3] = v[3]
+end
+return result
\ No newline at end of file
@@ -0,0 +1,240 @@
+package counter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterRefund(t *testing.T) {
+	clientMock := &ClientMock{}
+	size := 1000
+	limit := int64(100)
+	c := &Counter{store: NewRedisStore(clientMock), script: fwscr, alg: algFixed, size: size, limit: limit}
+	ctx := context.Background()
+	hash := rfscr.Hash()
+	value := 2
+	window := Result{}.window()
+
+	var i interface{}
+
+	e := errors.New("redis error")
+	clientMock.On("EvalSha", ctx, hash, []string{"1"}, value, size, limit, algFixed, window).Return(redis.NewCmdResult(i, e))
+	_, err := c.Refund(ctx, "1", Result{}, value)
+	require.Equal(t, e, err)
+
+	i = []interface{}{int64(1), int64(2)}
+	clientMock.On("EvalSha", ctx, hash, []string{"2"}, value, size, limit, algFixed, window).Return(redis.NewCmdResult(i, nil))
+	_, err = c.Refund(ctx, "2", Result{}, value)
+	require.Equal(t, ErrUnexpectedRedisResponse, err)
+
+	i = []interface{}{int64(1), int64(2), int64(100)}
+	clientMock.On("EvalSha", ctx, hash, []string{"3"}, value, size, limit, algFixed, window).Return(redis.NewCmdResult(i, nil))
+	result, err := c.Refund(ctx, "3", Result{}, value)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(2), result.Counter())
+	require.Equal(t, limit-2, result.Remainder())
+	require.Equal(t, msToDuration(100), result.TTL())
+
+	clientMock.AssertExpectations(t)
+}
+
+func TestRefund(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	size := time.Minute
+	limit := uint(100)
+	tests := map[string]*Counter{
+		"FixedWindow":   FixedWindow(client, size, limit),
+		"SlidingWindow": SlidingWindow(client, size, limit),
+		"TokenBucket":   TokenBucket(client, size/time.Duration(limit), limit),
+		"GCRA":          GCRA(client, size, limit),
+		"SlidingLog":    SlidingLog(client, size, limit),
+		"Quota":         Quota(client, Day, time.UTC, limit),
+	}
+
+	ctx := context.Background()
+	key := "key"
+	for name, counter := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := counter.Reset(ctx, key)
+			require.NoError(t, err)
+
+			result, err := counter.Refund(ctx, key, Result{}, 10)
+			require.NoError(t, err)
+			require.True(t, result.OK())
+			require.Equal(t, int64(0), result.Counter())
+			require.Equal(t, int64(100), result.Remainder())
+
+			taken, err := counter.Count(ctx, key, 100)
+			require.NoError(t, err)
+
+			result, err = counter.Refund(ctx, key, taken, 30)
+			require.NoError(t, err)
+			require.True(t, result.OK())
+			require.Equal(t, int64(70), result.Counter())
+			require.Equal(t, int64(30), result.Remainder())
+			require.True(t, result.TTL() > msToDuration(0) && result.TTL() <= 24*time.Hour)
+
+			_, err = counter.Refund(ctx, key, taken, -5)
+			require.Equal(t, ErrNegativeN, err)
+
+			result, err = counter.Peek(ctx, key)
+			require.NoError(t, err)
+			require.Equal(t, int64(70), result.Counter())
+
+			taken, err = counter.Count(ctx, key, 30)
+			require.NoError(t, err)
+			require.True(t, taken.OK())
+			require.Equal(t, int64(100), taken.Counter())
+
+			result, err = counter.Refund(ctx, key, taken, 101)
+			require.NoError(t, err)
+			require.True(t, result.OK())
+			require.Equal(t, int64(0), result.Counter())
+			require.Equal(t, int64(100), result.Remainder())
+		})
+	}
+}
+
+func TestRefundWindowEnded(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	testRefundWindowEnded(t, client)
+}
+
+func testRefundWindowEnded(t *testing.T, client RedisClient) {
+	ctx := context.Background()
+	size := 100 * time.Millisecond
+	key := "key"
+
+	t.Run("FixedWindow", func(t *testing.T) {
+		counter := FixedWindow(client, size, 100)
+		err := counter.Reset(ctx, key)
+		require.NoError(t, err)
+
+		taken, err := counter.Count(ctx, key, 5)
+		require.NoError(t, err)
+		require.True(t, taken.OK())
+
+		time.Sleep(taken.TTL() + 10*time.Millisecond) // wait for the next window to start
+
+		next, err := counter.Count(ctx, key, 5)
+		require.NoError(t, err)
+		require.Equal(t, int64(5), next.Counter())
+
+		result, err := counter.Refund(ctx, key, taken, 5) // the window of the taken result has ended
+		require.NoError(t, err)
+		require.True(t, result.OK())
+		require.Equal(t, int64(5), result.Counter())
+
+		result, err = counter.Refund(ctx, key, next, 5)
+		require.NoError(t, err)
+		require.Equal(t, int64(0), result.Counter())
+	})
+
+	t.Run("SlidingWindow", func(t *testing.T) {
+		counter := SlidingWindow(client, size, 100)
+		err := counter.Reset(ctx, key)
+		require.NoError(t, err)
+
+		taken, err := counter.Count(ctx, key, 100)
+		require.NoError(t, err)
+		require.True(t, taken.OK())
+
+		time.Sleep(taken.TTL() + 10*time.Millisecond) // wait for the next window to start
+
+		result, err := counter.Count(ctx, key, 20)
+		require.NoError(t, err)
+		require.False(t, result.OK())
+
+		result, err = counter.Refund(ctx, key, taken, 100) // the window of the taken result is the previous window
+		require.NoError(t, err)
+		require.True(t, result.OK())
+		require.Equal(t, int64(0), result.Counter())
+
+		taken, err = counter.Count(ctx, key, 10)
+		require.NoError(t, err)
+		require.True(t, taken.OK())
+
+		time.Sleep(taken.TTL() + size + 10*time.Millisecond) // wait for the window after the next window to start
+
+		result, err = counter.Count(ctx, key, 10)
+		require.NoError(t, err)
+		require.Equal(t, int64(10), result.Counter())
+
+		result, err = counter.Refund(ctx, key, taken, 10) // the window of the taken result is gone
+		require.NoError(t, err)
+		require.Equal(t, int64(10), result.Counter())
+	})
+
+	t.Run("Limiter", func(t *testing.T) {
+		limiter := NewLimiter(client, WithLimit(size, 100, WithName("x")), WithLimit(size, 100, WithName("y"), WithSlidingWindow()))
+		err := limiter.Reset(ctx, key)
+		require.NoError(t, err)
+
+		taken, err := limiter.LimitN(ctx, key, 5)
+		require.NoError(t, err)
+		require.True(t, taken.OK())
+
+		time.Sleep(taken.TTL() + size + 10*time.Millisecond) // wait for the window after the next window to start
+
+		_, err = limiter.LimitN(ctx, key, 5)
+		require.NoError(t, err)
+
+		result, err := limiter.Refund(ctx, key, taken, 5) // the windows of the taken result are gone
+		require.NoError(t, err)
+		require.Equal(t, int64(5), result.Limits()[0].Counter())
+		require.Equal(t, int64(5), result.Limits()[1].Counter())
+	})
+}
+
+func TestLimiterRefund(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	tests := map[string]Limiter{
+		"One": NewLimiter(client, WithLimit(time.Minute, 6, WithRate(2))),
+		"Two": NewLimiter(client, WithLimit(time.Minute, 6, WithRate(2)), WithLimit(time.Minute, 10, WithSlidingLog())),
+	}
+
+	key := "key"
+	for name, limiter := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := limiter.Reset(ctx, key)
+			require.NoError(t, err)
+
+			var taken Result
+			for i := 0; i < 3; i++ {
+				taken, err = limiter.Limit(ctx, key)
+				require.NoError(t, err)
+			}
+
+			result, err := limiter.Limit(ctx, key)
+			require.NoError(t, err)
+			require.False(t, result.OK())
+
+			result, err = limiter.Refund(ctx, key, taken, 2)
+			require.NoError(t, err)
+			require.True(t, result.OK())
+			require.Equal(t, int64(2), result.Counter())
+			require.Equal(t, int64(4), result.Remainder())
+
+			_, err = limiter.Refund(ctx, key, taken, -1)
+			require.Equal(t, ErrNegativeN, err)
+
+			result, err = limiter.Limit(ctx, key)
+			require.NoError(t, err)
+			require.True(t, result.OK())
+			require.Equal(t, int64(4), result.Counter())
+		})
+	}
+}
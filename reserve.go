@@ -0,0 +1,76 @@
+package counter
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"time"
+)
+
+// ErrReserveUnsupported is the error returned by Reserve when the counter does not use generic cell rate algorithm.
+var ErrReserveUnsupported = errors.New("counter: reservation requires generic cell rate algorithm")
+
+//go:embed reserve.lua
+var rvsrc string
+var rvscr = newScript("reserve", rvsrc)
+
+// Reserve books the value for the key in the future, even if incrementing the key value would fail now,
+// the caller must wait for the delay of the reservation before acting.
+// The reservation is OK unless the value exceeds the limit.
+//
+// Reserve requires the counter created with GCRA, otherwise it fails with ErrReserveUnsupported.
+func (c *Counter) Reserve(ctx context.Context, key string, value int) (*Reservation, error) {
+	if c.alg != algGCRA {
+		return nil, ErrReserveUnsupported
+	}
+	res, err := c.store.Run(ctx, rvscr, []string{key}, value, c.size, c.limit)
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := res.([]interface{})
+	if !ok || len(arr) != 5 {
+		return nil, ErrUnexpectedRedisResponse
+	}
+	r, err := newResult(arr[:3], c.limit)
+	if err != nil {
+		return nil, err
+	}
+	delay, ok := arr[3].(int64)
+	if !ok {
+		return nil, ErrUnexpectedRedisResponse
+	}
+	at, ok := arr[4].(int64)
+	if !ok {
+		return nil, ErrUnexpectedRedisResponse
+	}
+	return &Reservation{Result: r, c: c, key: key, value: value, delay: delay, at: at}, nil
+}
+
+// Reservation is counter reservation.
+type Reservation struct {
+	Result
+	c     *Counter
+	key   string
+	value int
+	delay int64
+	at    int64
+}
+
+// Delay is the time to wait before acting on the reservation.
+func (r *Reservation) Delay() time.Duration {
+	return time.Duration(r.delay) * time.Millisecond
+}
+
+//go:embed cancel.lua
+var cnsrc string
+var cnscr = newScript("cancel", cnsrc)
+
+// Cancel gives back the reserved value, so the next reservations may act earlier.
+// Cancel does nothing if the reservation is not OK or the delay of the reservation has already elapsed.
+func (r *Reservation) Cancel(ctx context.Context) error {
+	if !r.OK() {
+		return nil
+	}
+	_, err := r.c.store.Run(ctx, cnscr, []string{r.key}, r.value, r.c.size, r.c.limit, r.at)
+	return err
+}
@@ -0,0 +1,23 @@
+local function reserve(key, value, size, limit)
+	value = tonumber(value)
+	local t = redis.call("time")
+	local now = t[1] * 1000 + math.floor(t[2]/1000)
+	local interval = size / limit
+	local tat = redis.call("get", key)
+	if tat == false then
+		tat = now
+	else
+		tat = math.max(tonumber(tat), now)
+	end
+	if value > limit then
+		return { 0, math.min(limit, math.ceil((tat - now) / interval)), math.ceil(tat - now), 0, 0 }
+	end
+	local newTat = tat + value * interval
+	local delay = math.max(0, math.ceil(newTat - size - now))
+	local ttl = math.ceil(newTat - now)
+	if ttl > 0 then
+		redis.call("set", key, newTat, "px", ttl)
+	end
+	return { 1, math.min(limit, math.ceil((newTat - now) / interval)), ttl, delay, now + delay }
+end
+return reserve(KEYS[1], ARGV[1], ARGV[2], tonumber(ARGV[3]))
\ No newline at end of file
@@ -0,0 +1,122 @@
+package counter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReserve(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	c := GCRA(client, time.Second, 10)
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	r, err := c.Reserve(ctx, key, 10)
+	require.NoError(t, err)
+	require.True(t, r.OK())
+	require.Equal(t, int64(10), r.Counter())
+	require.Equal(t, int64(0), r.Remainder())
+	require.Equal(t, time.Duration(0), r.Delay())
+
+	r, err = c.Reserve(ctx, key, 1)
+	require.NoError(t, err)
+	require.True(t, r.OK())
+	require.InDelta(t, time.Millisecond*100, r.Delay(), float64(time.Millisecond*20))
+
+	r, err = c.Reserve(ctx, key, 2)
+	require.NoError(t, err)
+	require.True(t, r.OK())
+	require.InDelta(t, time.Millisecond*300, r.Delay(), float64(time.Millisecond*20))
+
+	err = r.Cancel(ctx)
+	require.NoError(t, err)
+
+	r, err = c.Reserve(ctx, key, 1)
+	require.NoError(t, err)
+	require.True(t, r.OK())
+	require.InDelta(t, time.Millisecond*200, r.Delay(), float64(time.Millisecond*20))
+
+	r, err = c.Reserve(ctx, key, 11)
+	require.NoError(t, err)
+	require.False(t, r.OK())
+	require.Equal(t, time.Duration(0), r.Delay())
+	err = r.Cancel(ctx)
+	require.NoError(t, err)
+
+	r, err = c.Reserve(ctx, key, 1)
+	require.NoError(t, err)
+	require.InDelta(t, time.Millisecond*300, r.Delay(), float64(time.Millisecond*20))
+
+	time.Sleep(r.Delay() + time.Millisecond*20)
+	err = r.Cancel(ctx) // the delay has elapsed
+	require.NoError(t, err)
+
+	r, err = c.Reserve(ctx, key, 1)
+	require.NoError(t, err)
+	require.True(t, r.Delay() > time.Millisecond*40) // the reserved value is not given back
+}
+
+func TestReserveUnsupported(t *testing.T) {
+	c := FixedWindow(&ClientMock{}, time.Second, 10)
+	_, err := c.Reserve(context.Background(), "key", 1)
+	require.Equal(t, ErrReserveUnsupported, err)
+}
+
+func TestCounterReserve(t *testing.T) {
+	clientMock := &ClientMock{}
+	size := 1000
+	limit := int64(10)
+	c := &Counter{store: NewRedisStore(clientMock), script: gcscr, alg: algGCRA, size: size, limit: limit}
+	ctx := context.Background()
+	hash := rvscr.Hash()
+
+	var i interface{}
+
+	e := errors.New("redis error")
+	clientMock.On("EvalSha", ctx, hash, []string{"1"}, 1, size, limit).Return(redis.NewCmdResult(i, e))
+	_, err := c.Reserve(ctx, "1", 1)
+	require.Equal(t, e, err)
+
+	i = []interface{}{int64(1), int64(2), int64(200)}
+	clientMock.On("EvalSha", ctx, hash, []string{"2"}, 1, size, limit).Return(redis.NewCmdResult(i, nil))
+	_, err = c.Reserve(ctx, "2", 1)
+	require.Equal(t, ErrUnexpectedRedisResponse, err)
+
+	i = []interface{}{int64(1), int64(2), int64(200), 0, 0}
+	clientMock.On("EvalSha", ctx, hash, []string{"3"}, 1, size, limit).Return(redis.NewCmdResult(i, nil))
+	_, err = c.Reserve(ctx, "3", 1)
+	require.Equal(t, ErrUnexpectedRedisResponse, err)
+
+	i = []interface{}{int64(1), int64(2), int64(200), int64(0), 0}
+	clientMock.On("EvalSha", ctx, hash, []string{"4"}, 1, size, limit).Return(redis.NewCmdResult(i, nil))
+	_, err = c.Reserve(ctx, "4", 1)
+	require.Equal(t, ErrUnexpectedRedisResponse, err)
+
+	i = []interface{}{1, int64(2), int64(200), int64(0), int64(0)}
+	clientMock.On("EvalSha", ctx, hash, []string{"5"}, 1, size, limit).Return(redis.NewCmdResult(i, nil))
+	_, err = c.Reserve(ctx, "5", 1)
+	require.Equal(t, ErrUnexpectedRedisResponse, err)
+
+	i = []interface{}{int64(1), int64(12), int64(1200), int64(200), int64(1000200)}
+	clientMock.On("EvalSha", ctx, hash, []string{"6"}, 2, size, limit).Return(redis.NewCmdResult(i, nil))
+	r, err := c.Reserve(ctx, "6", 2)
+	require.NoError(t, err)
+	require.True(t, r.OK())
+	require.Equal(t, msToDuration(200), r.Delay())
+	require.Equal(t, msToDuration(1200), r.TTL())
+
+	clientMock.On("EvalSha", ctx, cnscr.Hash(), []string{"6"}, 2, size, limit, int64(1000200)).Return(redis.NewCmdResult(int64(1), nil))
+	err = r.Cancel(ctx)
+	require.NoError(t, err)
+
+	clientMock.AssertExpectations(t)
+}
@@ -0,0 +1,16 @@
+local t = redis.call("time")
+local now = t[1] * 1000 + math.floor(t[2]/1000)
+local z = 0
+for i, key in ipairs(KEYS) do
+	z = z + 4
+	if ARGV[z] == "2" then
+		local size = tonumber(ARGV[z - 2])
+		local currWindowTime = now - now % size
+		redis.call("del", key .. ":" .. currWindowTime, key .. ":" .. currWindowTime - size, key .. ":" .. currWindowTime - size * 2)
+	elseif ARGV[z] == "5" then
+		redis.call("del", key, key .. ":sum")
+	else
+		redis.call("del", key)
+	end
+end
+return z / 4
\ No newline at end of file
@@ -0,0 +1,71 @@
+package counter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReset(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	size := 200 * time.Millisecond
+	limit := uint(2)
+	tests := map[string]Limiter{
+		"FixedWindow":   NewLimiter(client, WithLimit(size, limit, WithName("reset-FixedWindow"))),
+		"SlidingWindow": NewLimiter(client, WithLimit(size, limit, WithName("reset-SlidingWindow"), WithSlidingWindow())),
+		"TokenBucket":   NewLimiter(client, WithLimit(size, limit, WithName("reset-TokenBucket"), WithTokenBucket())),
+		"GCRA":          NewLimiter(client, WithLimit(size, limit, WithName("reset-GCRA"), WithGCRA())),
+		"SlidingLog":    NewLimiter(client, WithLimit(size, limit, WithName("reset-SlidingLog"), WithSlidingLog())),
+		"Quota":         NewLimiter(client, WithQuota(Day, time.UTC, limit, WithName("reset-Quota"))),
+		"Batch": NewLimiter(
+			client,
+			WithLimit(size, limit, WithName("reset-Batch-1")),
+			WithLimit(size, limit, WithName("reset-Batch-2"), WithSlidingWindow()),
+			WithLimit(size, limit, WithName("reset-Batch-3"), WithTokenBucket()),
+			WithLimit(size, limit, WithName("reset-Batch-4"), WithGCRA()),
+			WithLimit(size, limit, WithName("reset-Batch-5"), WithSlidingLog()),
+			WithQuota(Day, time.UTC, limit, WithName("reset-Batch-6")),
+		),
+	}
+
+	ctx := context.Background()
+	key := "key"
+	for name, limiter := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := limiter.Reset(ctx, key)
+			require.NoError(t, err)
+			pattern := "reset-" + name + "*" // the keys of the limiter only
+
+			result, err := limiter.Limit(ctx, key)
+			require.NoError(t, err)
+			require.True(t, result.OK())
+
+			time.Sleep(size + 50*time.Millisecond) // wait for the next window to start
+
+			result, err = limiter.Limit(ctx, key)
+			require.NoError(t, err)
+			require.True(t, result.OK())
+
+			keys, err := client.Keys(ctx, pattern).Result()
+			require.NoError(t, err)
+			require.NotEmpty(t, keys)
+
+			err = limiter.Reset(ctx, key)
+			require.NoError(t, err)
+
+			keys, err = client.Keys(ctx, pattern).Result()
+			require.NoError(t, err)
+			require.Empty(t, keys)
+
+			result, err = limiter.Peek(ctx, key)
+			require.NoError(t, err)
+			require.True(t, result.OK())
+			require.Equal(t, int64(0), result.Counter())
+		})
+	}
+}
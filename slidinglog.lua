@@ -0,0 +1,33 @@
+local function slidingLog(key, value, size, limit)
+	value = tonumber(value)
+	local t = redis.call("time")
+	local now = t[1] * 1000 + math.floor(t[2]/1000)
+	local sumKey = key .. ":sum"
+	local counter = tonumber(redis.call("hget", sumKey, "sum")) or 0
+	local expired = redis.call("zrangebyscore", key, "-inf", now - size)
+	if #expired > 0 then
+		for _, hit in ipairs(expired) do
+			counter = counter - tonumber(string.match(hit, ":(%d+)$"))
+		end
+		redis.call("zremrangebyscore", key, "-inf", now - size)
+		redis.call("hset", sumKey, "sum", counter)
+	end
+	local ok = 0
+	if counter + value <= limit then
+		ok = 1
+		if value > 0 then
+			local seq = redis.call("hincrby", sumKey, "seq", 1)
+			redis.call("zadd", key, now, seq .. ":" .. value)
+			counter = redis.call("hincrby", sumKey, "sum", value)
+			redis.call("pexpire", key, size)
+			redis.call("pexpire", sumKey, size)
+		end
+	end
+	local ttl = 0
+	local oldest = redis.call("zrange", key, 0, 0, "withscores")
+	if oldest[2] ~= nil then
+		ttl = oldest[2] + size - now
+	end
+	return { ok, counter, ttl }
+end
+return slidingLog(KEYS[1], ARGV[1], ARGV[2], tonumber(ARGV[3]))
\ No newline at end of file
@@ -0,0 +1,124 @@
+package counter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlidingLog(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	err := client.Del(context.Background(), "key", "key:sum").Err()
+	require.NoError(t, err)
+
+	testSlidingLog(t, client)
+}
+
+func testSlidingLog(t *testing.T, client RedisClient) {
+	ctx := context.Background()
+	key := "key"
+
+	size := time.Second
+	counter := SlidingLog(client, size, 100)
+
+	result, err := counter.Count(ctx, key, 101)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.Equal(t, int64(0), result.Counter())
+	require.Equal(t, int64(100), result.Remainder())
+	require.Equal(t, msToDuration(0), result.TTL())
+
+	result, err = counter.Count(ctx, key, 20)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(20), result.Counter())
+	require.Equal(t, int64(80), result.Remainder())
+	require.Equal(t, size, result.TTL())
+
+	result, err = counter.Count(ctx, key, 30)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(50), result.Counter())
+	require.Equal(t, int64(50), result.Remainder())
+	require.True(t, result.TTL() > msToDuration(0) && result.TTL() <= size)
+
+	result, err = counter.Count(ctx, key, 51)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.Equal(t, int64(50), result.Counter())
+	require.Equal(t, int64(50), result.Remainder())
+	require.True(t, result.TTL() > msToDuration(0) && result.TTL() <= size)
+
+	time.Sleep(msToDuration(500)) // wait for the half of the window to pass
+
+	result, err = counter.Count(ctx, key, 50)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(100), result.Counter())
+	require.Equal(t, int64(0), result.Remainder())
+	require.True(t, result.TTL() > msToDuration(0) && result.TTL() <= msToDuration(500))
+
+	time.Sleep(result.TTL() + msToDuration(100)) // wait for the first hits to expire
+
+	result, err = counter.Count(ctx, key, 20)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(70), result.Counter())
+	require.Equal(t, int64(30), result.Remainder())
+	require.True(t, result.TTL() > msToDuration(0) && result.TTL() <= msToDuration(500))
+}
+
+func TestSlidingLogWeight(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	err := client.Del(ctx, "key", "key:sum").Err()
+	require.NoError(t, err)
+
+	testSlidingLogWeight(t, client)
+
+	n, err := client.ZCard(ctx, "key").Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n) // one member per hit
+}
+
+func testSlidingLogWeight(t *testing.T, client RedisClient) {
+	ctx := context.Background()
+	key := "key"
+	counter := SlidingLog(client, time.Minute, 10000000)
+
+	result, err := counter.Count(ctx, key, 9000000)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(9000000), result.Counter())
+
+	result, err = counter.Count(ctx, key, 500)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(9000500), result.Counter())
+
+	result, err = counter.Refund(ctx, key, result, 700)
+	require.NoError(t, err)
+	require.Equal(t, int64(8999800), result.Counter())
+
+	result, err = counter.Peek(ctx, key)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(8999800), result.Counter())
+
+	result, err = counter.Count(ctx, key, 1000201)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.Equal(t, int64(8999800), result.Counter())
+
+	result, err = counter.Count(ctx, key, 1000200)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(10000000), result.Counter())
+}
@@ -13,10 +13,15 @@ func TestSlidingWindow(t *testing.T) {
 	client := redis.NewClient(&redis.Options{})
 	defer client.Close()
 
+	err := client.Del(context.Background(), "key").Err()
+	require.NoError(t, err)
+
+	testSlidingWindow(t, client)
+}
+
+func testSlidingWindow(t *testing.T, client RedisClient) {
 	ctx := context.Background()
 	key := "key"
-	err := client.Del(ctx, key).Err()
-	require.NoError(t, err)
 
 	size := time.Second
 	counter := SlidingWindow(client, size, 100)
@@ -0,0 +1,54 @@
+package counter
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Store runs the scripts of the package.
+//
+// The store may dispatch on the script name to run its own implementation of the script,
+// the arguments of the script are the same as the arguments of the Lua script.
+type Store interface {
+	Run(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// Script is the script of the package.
+type Script struct {
+	name   string
+	source string
+	script *redis.Script
+}
+
+func newScript(name, source string) *Script {
+	return &Script{name: name, source: source, script: redis.NewScript(source)}
+}
+
+// Name is the name of the script, equal to the name of the Lua script file without extension.
+func (s *Script) Name() string {
+	return s.name
+}
+
+// Source is the source of the Lua script.
+func (s *Script) Source() string {
+	return s.source
+}
+
+// Hash is the SHA1 digest of the Lua script.
+func (s *Script) Hash() string {
+	return s.script.Hash()
+}
+
+// NewRedisStore creates new store which runs the Lua scripts using Redis.
+func NewRedisStore(client RedisClient) Store {
+	return &redisStore{client: client}
+}
+
+type redisStore struct {
+	client RedisClient
+}
+
+func (s *redisStore) Run(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error) {
+	return script.script.Run(ctx, s.client, keys, args...).Result()
+}
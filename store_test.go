@@ -0,0 +1,77 @@
+package counter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+type storeFunc func(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error)
+
+func (fn storeFunc) Run(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error) {
+	return fn(ctx, script, keys, args...)
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	var names []string
+	var res interface{}
+	var e error
+	store := storeFunc(func(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error) {
+		names = append(names, script.Name())
+		return res, e
+	})
+
+	c := NewCounter(store, WithLimit(time.Second, 10, WithSlidingWindow()))
+	res = []interface{}{int64(1), int64(2), int64(100)}
+	result, err := c.Count(ctx, "key", 1)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(8), result.Remainder())
+
+	res = []interface{}{int64(1), int64(2)}
+	_, err = c.Count(ctx, "key", 1)
+	require.Equal(t, ErrUnexpectedRedisResponse, err)
+
+	e = errors.New("store error")
+	_, err = c.Count(ctx, "key", 1)
+	require.Equal(t, e, err)
+
+	limiter := NewStoreLimiter(store, WithLimit(time.Second, 10), WithLimit(time.Minute, 100))
+	res, e = []interface{}{int64(1), int64(1), int64(100), int64(1), int64(1), int64(100)}, nil
+	_, err = limiter.Limit(ctx, "key")
+	require.NoError(t, err)
+	_, err = limiter.Peek(ctx, "key")
+	require.NoError(t, err)
+	_, err = limiter.Refund(ctx, "key", Result{}, 1)
+	require.NoError(t, err)
+	err = limiter.Reset(ctx, "key")
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"slidingwindow", "slidingwindow", "slidingwindow", "limit", "peek", "refund", "reset"}, names)
+}
+
+func TestRedisStore(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	err := client.Del(ctx, "key").Err()
+	require.NoError(t, err)
+
+	store := NewRedisStore(client)
+	res, err := store.Run(ctx, fwscr, []string{"key"}, 1, 1000, 10)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{int64(1), int64(1), int64(1000)}, res)
+
+	result, err := NewCounter(store, WithLimit(time.Second, 10)).Count(ctx, "key", 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), result.Counter())
+
+	require.Equal(t, "fixedwindow", fwscr.Name())
+	require.Equal(t, fwsrc, fwscr.Source())
+}
@@ -0,0 +1,34 @@
+package counter
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrTimeout is the error returned when the store does not reply within the timeout set with WithTimeout.
+var ErrTimeout = errors.New("counter: timeout")
+
+// WithTimeout limits the time the limiter or the counter waits for the store on each call,
+// independent of the deadline of the context. The call is canceled after the timeout and fails with ErrTimeout,
+// which is treated as unavailable store by the failure policy and the circuit breaker.
+func WithTimeout(timeout time.Duration) Option {
+	return option(func(c *config) {
+		c.timeout = timeout
+	})
+}
+
+type timeoutStore struct {
+	store   Store
+	timeout time.Duration
+}
+
+func (s *timeoutStore) Run(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error) {
+	tctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	res, err := s.store.Run(tctx, script, keys, args...)
+	if err != nil && ctx.Err() == nil && tctx.Err() == context.DeadlineExceeded {
+		return nil, ErrTimeout
+	}
+	return res, err
+}
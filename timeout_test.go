@@ -0,0 +1,53 @@
+package counter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout(t *testing.T) {
+	memory := NewMemory()
+	var delay time.Duration
+	store := storeFunc(func(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error) {
+		select {
+		case <-time.After(delay):
+			return memory.Run(ctx, script, keys, args...)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+	ctx := context.Background()
+	timeout := time.Millisecond * 20
+	p := WithLimit(time.Second, 10, WithName("x"))
+
+	c := NewCounter(store, p, WithTimeout(timeout))
+	result, err := c.Count(ctx, "key", 1)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+
+	delay = time.Second
+	start := time.Now()
+	_, err = c.Count(ctx, "key", 1)
+	require.Equal(t, ErrTimeout, err)
+	require.True(t, time.Since(start) < delay)
+
+	cctx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+	defer cancel()
+	_, err = NewCounter(store, p, WithTimeout(time.Minute)).Count(cctx, "key", 1)
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	limiter := NewStoreLimiter(store, p, WithLimit(time.Minute, 100), WithTimeout(timeout), WithFailOpen())
+	result, err = limiter.Limit(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.True(t, result.Degraded())
+
+	limiter = NewStoreLimiter(store, p, WithTimeout(timeout), WithCircuitBreaker(1, time.Minute))
+	_, err = limiter.Limit(ctx, "key")
+	require.Equal(t, ErrTimeout, err)
+	_, err = limiter.Limit(ctx, "key")
+	require.Equal(t, ErrCircuitOpen, err)
+}
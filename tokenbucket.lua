@@ -0,0 +1,21 @@
+local function tokenBucket(key, value, size, limit)
+	value = tonumber(value)
+	local t = redis.call("time")
+	local now = t[1] * 1000 + math.floor(t[2]/1000)
+	local tokens = limit
+	local bucket = redis.call("hmget", key, "tokens", "time")
+	if bucket[1] ~= false then
+		tokens = math.min(limit, bucket[1] + (now - bucket[2]) * limit / size)
+	end
+	if tokens < value then
+		return { 0, limit - math.floor(tokens), math.ceil((value - tokens) * size / limit) }
+	end
+	tokens = tokens - value
+	local ttl = math.ceil((limit - tokens) * size / limit)
+	if ttl > 0 then
+		redis.call("hset", key, "tokens", tokens, "time", now)
+		redis.call("pexpire", key, ttl)
+	end
+	return { 1, limit - math.floor(tokens), ttl }
+end
+return tokenBucket(KEYS[1], ARGV[1], ARGV[2], tonumber(ARGV[3]))
\ No newline at end of file
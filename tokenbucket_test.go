@@ -0,0 +1,85 @@
+package counter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucket(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	rate := 10 * time.Millisecond
+	counter := TokenBucket(client, rate, 100)
+
+	result, err := counter.Count(ctx, key, 101)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.Equal(t, int64(0), result.Counter())
+	require.Equal(t, int64(100), result.Remainder())
+	require.Equal(t, rate, result.TTL())
+
+	result, err = counter.Count(ctx, key, 20)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.Equal(t, int64(20), result.Counter())
+	require.Equal(t, int64(80), result.Remainder())
+	require.Equal(t, rate*20, result.TTL())
+
+	result, err = counter.Count(ctx, key, 30)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.True(t, result.Counter() > 45 && result.Counter() <= 50)
+	require.True(t, result.Remainder() >= 50 && result.Remainder() < 55)
+	require.True(t, result.TTL() > rate*45 && result.TTL() <= rate*50)
+
+	result, err = counter.Count(ctx, key, 51)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.True(t, result.Counter() > 45 && result.Counter() <= 50)
+	require.True(t, result.Remainder() >= 50 && result.Remainder() < 55)
+	require.True(t, result.TTL() > msToDuration(0) && result.TTL() <= rate*6)
+
+	time.Sleep(result.TTL() + rate*20) // wait for the bucket to be refilled with 20 tokens
+
+	result, err = counter.Count(ctx, key, 70)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.True(t, result.Counter() > 95 && result.Counter() <= 100)
+	require.True(t, result.Remainder() >= 0 && result.Remainder() < 5)
+	require.True(t, result.TTL() > rate*95 && result.TTL() <= rate*100)
+}
+
+func TestTokenBucketSize(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	key := "key"
+	err := client.Del(ctx, key).Err()
+	require.NoError(t, err)
+
+	counter := TokenBucket(client, 100*time.Microsecond, 5)
+	require.Equal(t, 1, counter.size)
+
+	result, err := counter.Count(ctx, key, 6)
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.Equal(t, time.Millisecond, result.TTL())
+
+	require.Equal(t, 2, TokenBucket(client, 1500*time.Microsecond, 1).size)
+	require.Panics(t, func() { TokenBucket(client, 0, 5) })
+	require.Panics(t, func() { TokenBucket(client, time.Millisecond, 0) })
+	require.Panics(t, func() { WithLimit(time.Second, 0, WithTokenBucket()) })
+	require.Panics(t, func() { WithLimit(0, 5) })
+	require.Equal(t, 1, WithLimit(time.Microsecond, 5, WithTokenBucket()).size)
+}
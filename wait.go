@@ -0,0 +1,85 @@
+package counter
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrExceedsLimit is the error returned by Wait when the limit is exceeded by a single application.
+var ErrExceedsLimit = errors.New("counter: value exceeds limit")
+
+// ErrWaitDeadline is the error returned by Wait when the context deadline comes before the limit allows the key.
+var ErrWaitDeadline = errors.New("counter: wait exceeds context deadline")
+
+var jitter = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// Wait applies the limit, blocks until the limit is applied successfully, see WaitN.
+func Wait(ctx context.Context, lt Limiter, key string) (Result, error) {
+	return WaitN(ctx, lt, key, 1)
+}
+
+// WaitN applies the limit n times at once, blocks until the limit is applied successfully.
+//
+// While the limit fails, WaitN sleeps for the TTL of the result plus a random jitter up to 1/10 of the TTL,
+// so the waiters do not wake up all at once at the start of the next window.
+// WaitN fails early with ErrWaitDeadline if the context deadline comes before the TTL elapses,
+// and with ErrExceedsLimit if the limit fails while the counter is zero,
+// which means the limit never allows n applications at once.
+// The last result is returned with the error.
+func WaitN(ctx context.Context, lt Limiter, key string, n int) (Result, error) {
+	for {
+		r, err := lt.LimitN(ctx, key, n)
+		if err != nil {
+			return r, err
+		}
+		if r.OK() {
+			return r, nil
+		}
+		if exceeds(r) {
+			return r, ErrExceedsLimit
+		}
+		d := r.TTL()
+		d += randomDuration(d / 10)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < r.TTL() {
+			return r, ErrWaitDeadline
+		}
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return r, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// exceeds checks if any failed limit of the not degraded result has zero counter.
+func exceeds(r Result) bool {
+	if r.degraded {
+		return false
+	}
+	if r.limits == nil {
+		return !r.OK() && r.counter == 0
+	}
+	for _, v := range r.limits {
+		if !v.OK() && v.counter == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func randomDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	jitter.Lock()
+	defer jitter.Unlock()
+	return time.Duration(jitter.Int63n(int64(max)))
+}
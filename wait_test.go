@@ -0,0 +1,63 @@
+package counter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWait(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	ctx := context.Background()
+	size := time.Millisecond * 200
+	limiter := NewLimiter(client, WithLimit(size, 2), WithLimit(size, 3, WithSlidingWindow()))
+	key := "key"
+	err := limiter.Reset(ctx, key)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		result, err := Wait(ctx, limiter, key)
+		require.NoError(t, err)
+		require.True(t, result.OK())
+	}
+
+	start := time.Now()
+	result, err := Wait(ctx, limiter, key)
+	require.NoError(t, err)
+	require.True(t, result.OK())
+	require.True(t, time.Since(start) >= size/2)
+
+	result, err = WaitN(ctx, limiter, key, 3)
+	require.Equal(t, ErrExceedsLimit, err)
+	require.False(t, result.OK())
+
+	ctx1, cancel1 := context.WithTimeout(ctx, time.Millisecond*20)
+	defer cancel1()
+	_, err = WaitN(ctx1, limiter, key, 2)
+	require.Equal(t, ErrWaitDeadline, err)
+
+	ctx2, cancel2 := context.WithCancel(ctx)
+	time.AfterFunc(time.Millisecond*20, cancel2)
+	_, err = WaitN(ctx2, limiter, key, 2)
+	require.Equal(t, context.Canceled, err)
+}
+
+func TestWaitError(t *testing.T) {
+	clientMock := &ClientMock{}
+	limiter := NewLimiter(clientMock, WithLimit(time.Second, 1, WithName("x")))
+	ctx := context.Background()
+
+	var i interface{}
+	e := errors.New("redis error")
+	clientMock.On("EvalSha", ctx, fwscr.Hash(), []string{"x:key"}, 1, 1000, int64(1)).Return(redis.NewCmdResult(i, e))
+	_, err := Wait(ctx, limiter, "key")
+	require.Equal(t, e, err)
+
+	clientMock.AssertExpectations(t)
+}